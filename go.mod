@@ -2,11 +2,15 @@ module github.com/mineleaguedev/luximo
 
 go 1.17
 
+require (
+	github.com/hashicorp/go-version v1.4.0
+	github.com/nwaples/rardecode v1.1.3
+)
+
 require (
 	github.com/andybalholm/brotli v1.0.4 // indirect
 	github.com/fasthttp/router v1.4.7 // indirect
 	github.com/fsnotify/fsnotify v1.5.1 // indirect
-	github.com/hashicorp/go-version v1.4.0 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/klauspost/compress v1.15.0 // indirect
 	github.com/magiconair/properties v1.8.5 // indirect
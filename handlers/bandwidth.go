@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/mineleaguedev/luximo/services"
+	"github.com/valyala/fasthttp"
+)
+
+func (h *Handler) BandwidthHandler(ctx *fasthttp.RequestCtx) {
+	h.writeBandwidth(ctx)
+}
+
+func (h *Handler) BandwidthUpdateHandler(ctx *fasthttp.RequestCtx) {
+	bandwidth := h.services.GetBandwidth()
+	if err := json.Unmarshal(ctx.PostBody(), &bandwidth); err != nil {
+		h.writeError(ctx, services.NewError(models.ErrorInvalidRequest, "invalid bandwidth limits", err))
+		return
+	}
+
+	if err := h.services.SetBandwidth(bandwidth); err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeBandwidth(ctx)
+}
+
+func (h *Handler) writeBandwidth(ctx *fasthttp.RequestCtx) {
+	response, err := json.Marshal(&models.BandwidthResponse{
+		Success:   true,
+		Bandwidth: h.services.GetBandwidth(),
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/mineleaguedev/luximo/services"
+	"github.com/valyala/fasthttp"
+)
+
+var errorStatuses = map[string]int{
+	models.ErrorUpstreamUnreachable: 502,
+	models.ErrorUpstreamRejected:    502,
+	models.ErrorChecksumMismatch:    502,
+	models.ErrorNotFound:            404,
+	models.ErrorConflict:            409,
+	models.ErrorDiskFull:            507,
+	models.ErrorInvalidConfig:       500,
+	models.ErrorInvalidRequest:      400,
+	models.ErrorUnauthorized:        401,
+	models.ErrorShuttingDown:        503,
+	models.ErrorCanceled:            503,
+	models.ErrorInternal:            500,
+}
+
+func (h *Handler) writeError(ctx *fasthttp.RequestCtx, err error) {
+	code := services.ErrorCode(err)
+
+	status, ok := errorStatuses[code]
+	if !ok {
+		status = 500
+	}
+
+	response, err := json.Marshal(&models.Error{
+		Success: false,
+		Code:    code,
+		Message: err.Error(),
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	ctx.Error(string(response), status)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+func (h *Handler) EventsHandler(ctx *fasthttp.RequestCtx) {
+	jobID := string(ctx.QueryArgs().Peek("job"))
+	events, unsubscribe := h.services.Subscribe(jobID)
+
+	var finished bool
+	if jobID != "" {
+		job, err := h.services.GetJob(jobID)
+		if err != nil {
+			unsubscribe()
+			h.writeError(ctx, err)
+			return
+		}
+		finished = job.FinishedAt != nil
+	}
+
+	ctx.SetContentType("text/event-stream")
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+	ctx.Response.Header.Set("X-Accel-Buffering", "no")
+	ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		if finished {
+			job, _ := h.services.GetJob(jobID)
+			writeEvent(w, models.Event{
+				Type:  models.EventSyncCompleted,
+				JobID: job.ID,
+				Kind:  job.Kind,
+				Items: job.Progress.ItemsDone,
+				Bytes: job.Progress.BytesDone,
+				State: job.State,
+				Error: job.Error,
+				Code:  job.ErrorCode,
+				Time:  *job.FinishedAt,
+			})
+			return
+		}
+
+		if _, err := w.WriteString(": connected\n\n"); err != nil {
+			return
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(15 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := writeEvent(w, event); err != nil {
+					return
+				}
+				if jobID != "" && event.Type == models.EventSyncCompleted {
+					return
+				}
+			case <-ticker.C:
+				if _, err := w.WriteString(": ping\n\n"); err != nil {
+					return
+				}
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+func writeEvent(w *bufio.Writer, event models.Event) error {
+	data, err := json.Marshal(&event)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.WriteString("event: " + event.Type + "\ndata: " + string(data) + "\n\n"); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/valyala/fasthttp"
+)
+
+func (h *Handler) GCHandler(ctx *fasthttp.RequestCtx) {
+	if ctx.QueryArgs().GetBool("async") {
+		job, err := h.services.StartJob("gc", h.services.CollectGarbage)
+		if err != nil {
+			h.writeError(ctx, err)
+			return
+		}
+
+		h.writeJob(ctx, job)
+		return
+	}
+
+	if _, err := h.services.RunJob("gc", h.services.CollectGarbage); err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	response, err := json.Marshal(&models.Response{
+		Success: true,
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
@@ -2,16 +2,19 @@ package handlers
 
 import (
 	"github.com/fasthttp/router"
+	"github.com/mineleaguedev/luximo/models"
 	"github.com/mineleaguedev/luximo/services"
 )
 
 type Handler struct {
 	services *services.Service
+	config   models.Config
 }
 
-func NewHandler(services *services.Service) *Handler {
+func NewHandler(services *services.Service, config models.Config) *Handler {
 	return &Handler{
 		services: services,
+		config:   config,
 	}
 }
 
@@ -22,6 +25,56 @@ func (h *Handler) InitRoutes() *router.Router {
 	r.PUT("/map", h.MapsUpdateHandler)
 	r.PUT("/velocity", h.VelocityUpdateHandler)
 	r.PUT("/paper", h.PaperUpdateHandler)
+	r.POST("/gc", h.GCHandler)
+	r.GET("/bandwidth", h.BandwidthHandler)
+	r.PUT("/bandwidth", h.BandwidthUpdateHandler)
+	r.GET("/ports", h.PortsHandler)
+	r.GET("/servers", h.ProcessesHandler)
+	r.GET("/servers/{type}/{name}", h.ProcessHandler)
+	r.POST("/servers/{type}/{name}/start", h.ServerStartHandler)
+	r.POST("/servers/{type}/{name}/stop", h.ServerStopHandler)
+	r.POST("/servers/{type}/{name}/restart", h.ServerRestartHandler)
+	r.GET("/proxy", h.ProxyServersHandler)
+	r.POST("/proxy", h.ProxyServerCreateHandler)
+	r.PUT("/proxy", h.ProxyServersUpdateHandler)
+	r.DELETE("/proxy/{name}", h.ProxyServerDeleteHandler)
+	r.GET("/lobby", h.LobbyServersHandler)
+	r.POST("/lobby", h.LobbyServerCreateHandler)
+	r.DELETE("/lobby/{name}", h.LobbyServerDeleteHandler)
+	r.GET("/mini", h.MiniServersHandler)
+	r.POST("/mini", h.MiniServerCreateHandler)
+	r.DELETE("/mini/{name}", h.MiniServerDeleteHandler)
+	r.GET("/mega", h.MegaServersHandler)
+	r.POST("/mega", h.MegaServerCreateHandler)
+	r.PUT("/mega/{name}", h.MegaServerUpdateHandler)
+	r.DELETE("/mega/{name}", h.MegaServerDeleteHandler)
+	r.GET("/jobs", h.JobsHandler)
+	r.GET("/jobs/{id}", h.JobHandler)
+	r.GET("/events", h.EventsHandler)
+	r.GET("/status", h.StatusHandler)
+	r.GET("/ready", h.ReadyHandler)
+
+	if h.config.Mirror || h.config.Registry {
+		r.GET("/plugin", h.MirrorPluginsHandler)
+		r.GET("/plugin/{name}/{version}", h.MirrorPluginHandler)
+		r.GET("/map", h.MirrorMapsHandler)
+		r.GET("/map/{minigame}/{format}/{map}/{version}/{file}", h.MirrorMapHandler)
+		r.GET("/paper", h.MirrorPaperHandler)
+		r.GET("/paper/{version}", h.MirrorPaperVersionHandler)
+		r.GET("/velocity", h.MirrorVelocityHandler)
+		r.GET("/velocity/{version}", h.MirrorVelocityVersionHandler)
+		r.HEAD("/plugin/{name}/{version}", h.MirrorPluginHandler)
+		r.HEAD("/map/{minigame}/{format}/{map}/{version}/{file}", h.MirrorMapHandler)
+		r.HEAD("/paper/{version}", h.MirrorPaperVersionHandler)
+		r.HEAD("/velocity/{version}", h.MirrorVelocityVersionHandler)
+	}
+
+	if h.config.Registry {
+		r.POST("/plugin/{name}/{version}", h.PluginPublishHandler)
+		r.POST("/map/{minigame}/{format}/{map}/{version}", h.MapPublishHandler)
+		r.POST("/paper/{version}", h.PaperPublishHandler)
+		r.POST("/velocity/{version}", h.VelocityPublishHandler)
+	}
 
 	return r
 }
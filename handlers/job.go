@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/valyala/fasthttp"
+)
+
+func (h *Handler) JobHandler(ctx *fasthttp.RequestCtx) {
+	job, err := h.services.GetJob(ctx.UserValue("id").(string))
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	response, err := json.Marshal(&models.JobResponse{
+		Success: true,
+		Job:     job,
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
+
+func (h *Handler) JobsHandler(ctx *fasthttp.RequestCtx) {
+	response, err := json.Marshal(&models.JobsResponse{
+		Success: true,
+		Jobs:    h.services.GetJobs(),
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
+
+func (h *Handler) writeJob(ctx *fasthttp.RequestCtx, job models.Job) {
+	response, err := json.Marshal(&models.JobResponse{
+		Success: true,
+		Job:     job,
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	ctx.SetStatusCode(202)
+	ctx.Response.Header.Set("Location", "/jobs/"+job.ID)
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
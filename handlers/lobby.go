@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/mineleaguedev/luximo/services"
+	"github.com/valyala/fasthttp"
+)
+
+func (h *Handler) LobbyServersHandler(ctx *fasthttp.RequestCtx) {
+	servers, err := h.services.GetLobbyServers()
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeServers(ctx, servers)
+}
+
+func (h *Handler) LobbyServerCreateHandler(ctx *fasthttp.RequestCtx) {
+	var request models.CreateServerRequest
+	if len(ctx.PostBody()) > 0 {
+		if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
+			h.writeError(ctx, services.NewError(models.ErrorInvalidRequest, "invalid lobby server request", err))
+			return
+		}
+	}
+
+	server, err := h.services.CreateLobbyServer(request)
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	ctx.SetStatusCode(201)
+	h.writeServer(ctx, server)
+}
+
+func (h *Handler) LobbyServerDeleteHandler(ctx *fasthttp.RequestCtx) {
+	if err := h.services.DeleteLobbyServer(ctx.UserValue("name").(string)); err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	response, err := json.Marshal(&models.Response{
+		Success: true,
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
@@ -7,17 +7,19 @@ import (
 )
 
 func (h *Handler) MapsUpdateHandler(ctx *fasthttp.RequestCtx) {
-	if err := h.services.UpdateMaps(); err != nil {
-		response, err := json.Marshal(&models.Error{
-			Success: false,
-			Message: err.Error(),
-		})
+	if ctx.QueryArgs().GetBool("async") {
+		job, err := h.services.StartJob("map", h.services.UpdateMaps)
 		if err != nil {
-			ctx.Error(err.Error(), 500)
+			h.writeError(ctx, err)
 			return
 		}
 
-		ctx.Error(string(response), 500)
+		h.writeJob(ctx, job)
+		return
+	}
+
+	if _, err := h.services.RunJob("map", h.services.UpdateMaps); err != nil {
+		h.writeError(ctx, err)
 		return
 	}
 
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/mineleaguedev/luximo/services"
+	"github.com/valyala/fasthttp"
+)
+
+func (h *Handler) MegaServersHandler(ctx *fasthttp.RequestCtx) {
+	servers, err := h.services.GetMegaServers()
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeServers(ctx, servers)
+}
+
+func (h *Handler) MegaServerCreateHandler(ctx *fasthttp.RequestCtx) {
+	var request models.CreateServerRequest
+	if len(ctx.PostBody()) > 0 {
+		if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
+			h.writeError(ctx, services.NewError(models.ErrorInvalidRequest, "invalid mega server request", err))
+			return
+		}
+	}
+
+	server, err := h.services.CreateMegaServer(request)
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	ctx.SetStatusCode(201)
+	h.writeServer(ctx, server)
+}
+
+func (h *Handler) MegaServerDeleteHandler(ctx *fasthttp.RequestCtx) {
+	if err := h.services.DeleteMegaServer(ctx.UserValue("name").(string)); err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	response, err := json.Marshal(&models.Response{
+		Success: true,
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
+
+func (h *Handler) MegaServerUpdateHandler(ctx *fasthttp.RequestCtx) {
+	server, err := h.services.UpdateMegaServer(ctx.UserValue("name").(string))
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeServer(ctx, server)
+}
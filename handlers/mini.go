@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/mineleaguedev/luximo/services"
+	"github.com/valyala/fasthttp"
+)
+
+func (h *Handler) MiniServersHandler(ctx *fasthttp.RequestCtx) {
+	servers, err := h.services.GetMiniServers()
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeServers(ctx, servers)
+}
+
+func (h *Handler) MiniServerCreateHandler(ctx *fasthttp.RequestCtx) {
+	var request models.CreateServerRequest
+	if len(ctx.PostBody()) > 0 {
+		if err := json.Unmarshal(ctx.PostBody(), &request); err != nil {
+			h.writeError(ctx, services.NewError(models.ErrorInvalidRequest, "invalid mini server request", err))
+			return
+		}
+	}
+
+	server, err := h.services.CreateMiniServer(request)
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	ctx.SetStatusCode(201)
+	h.writeServer(ctx, server)
+}
+
+func (h *Handler) MiniServerDeleteHandler(ctx *fasthttp.RequestCtx) {
+	if err := h.services.DeleteMiniServer(ctx.UserValue("name").(string)); err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	response, err := json.Marshal(&models.Response{
+		Success: true,
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/valyala/fasthttp"
+)
+
+func (h *Handler) MirrorPluginsHandler(ctx *fasthttp.RequestCtx) {
+	response, err := h.services.GetMirrorPlugins()
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeListing(ctx, response)
+}
+
+func (h *Handler) MirrorPluginHandler(ctx *fasthttp.RequestCtx) {
+	path, err := h.services.GetMirrorPluginFile(ctx.UserValue("name").(string), ctx.UserValue("version").(string))
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	fasthttp.ServeFileUncompressed(ctx, path)
+}
+
+func (h *Handler) MirrorMapsHandler(ctx *fasthttp.RequestCtx) {
+	response, err := h.services.GetMirrorMaps()
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeListing(ctx, response)
+}
+
+func (h *Handler) MirrorMapHandler(ctx *fasthttp.RequestCtx) {
+	path, err := h.services.GetMirrorMapFile(
+		ctx.UserValue("minigame").(string),
+		ctx.UserValue("format").(string),
+		ctx.UserValue("map").(string),
+		ctx.UserValue("version").(string),
+		ctx.UserValue("file").(string),
+	)
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	fasthttp.ServeFileUncompressed(ctx, path)
+}
+
+func (h *Handler) MirrorPaperHandler(ctx *fasthttp.RequestCtx) {
+	response, err := h.services.GetMirrorPaper()
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeListing(ctx, response)
+}
+
+func (h *Handler) MirrorPaperVersionHandler(ctx *fasthttp.RequestCtx) {
+	path, err := h.services.GetMirrorPaperFile(ctx.UserValue("version").(string))
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	fasthttp.ServeFileUncompressed(ctx, path)
+}
+
+func (h *Handler) MirrorVelocityHandler(ctx *fasthttp.RequestCtx) {
+	response, err := h.services.GetMirrorVelocity()
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeListing(ctx, response)
+}
+
+func (h *Handler) MirrorVelocityVersionHandler(ctx *fasthttp.RequestCtx) {
+	path, err := h.services.GetMirrorVelocityFile(ctx.UserValue("version").(string))
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	fasthttp.ServeFileUncompressed(ctx, path)
+}
+
+func (h *Handler) writeListing(ctx *fasthttp.RequestCtx, listing interface{}) {
+	response, err := json.Marshal(listing)
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	sum := sha256.Sum256(response)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	ctx.Response.Header.Set("ETag", etag)
+
+	if string(ctx.Request.Header.Peek("If-None-Match")) == etag {
+		ctx.SetStatusCode(304)
+		return
+	}
+
+	ctx.SetContentType("application/json")
+	_, err = ctx.Write(response)
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
@@ -7,17 +7,19 @@ import (
 )
 
 func (h *Handler) PaperUpdateHandler(ctx *fasthttp.RequestCtx) {
-	if err := h.services.UpdatePaper(); err != nil {
-		response, err := json.Marshal(&models.Error{
-			Success: false,
-			Message: err.Error(),
-		})
+	if ctx.QueryArgs().GetBool("async") {
+		job, err := h.services.StartJob("paper", h.services.UpdatePaper)
 		if err != nil {
-			ctx.Error(err.Error(), 500)
+			h.writeError(ctx, err)
 			return
 		}
 
-		ctx.Error(string(response), 500)
+		h.writeJob(ctx, job)
+		return
+	}
+
+	if _, err := h.services.RunJob("paper", h.services.UpdatePaper); err != nil {
+		h.writeError(ctx, err)
 		return
 	}
 
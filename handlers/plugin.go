@@ -7,17 +7,19 @@ import (
 )
 
 func (h *Handler) PluginsUpdateHandler(ctx *fasthttp.RequestCtx) {
-	if err := h.services.UpdatePlugins(); err != nil {
-		response, err := json.Marshal(&models.Error{
-			Success: false,
-			Message: err.Error(),
-		})
+	if ctx.QueryArgs().GetBool("async") {
+		job, err := h.services.StartJob("plugin", h.services.UpdatePlugins)
 		if err != nil {
-			ctx.Error(err.Error(), 500)
+			h.writeError(ctx, err)
 			return
 		}
 
-		ctx.Error(string(response), 500)
+		h.writeJob(ctx, job)
+		return
+	}
+
+	if _, err := h.services.RunJob("plugin", h.services.UpdatePlugins); err != nil {
+		h.writeError(ctx, err)
 		return
 	}
 
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/valyala/fasthttp"
+)
+
+func (h *Handler) PortsHandler(ctx *fasthttp.RequestCtx) {
+	response, err := json.Marshal(&models.PortsResponse{
+		Success: true,
+		Ports:   h.services.GetPorts(),
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/mineleaguedev/luximo/services"
+	"github.com/valyala/fasthttp"
+	"io/ioutil"
+	"strings"
+)
+
+func (h *Handler) PluginPublishHandler(ctx *fasthttp.RequestCtx) {
+	if !h.authorizePublish(ctx) {
+		return
+	}
+
+	pluginFileBytes, err := uploadedFile(ctx, "file")
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	response, err := h.services.PublishPlugin(ctx.UserValue("name").(string), ctx.UserValue("version").(string), pluginFileBytes)
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writePublish(ctx, response)
+}
+
+func (h *Handler) MapPublishHandler(ctx *fasthttp.RequestCtx) {
+	if !h.authorizePublish(ctx) {
+		return
+	}
+
+	mapWorldFileBytes, err := uploadedFile(ctx, "world")
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	mapConfigFileBytes, err := uploadedFile(ctx, "config")
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	response, err := h.services.PublishMap(
+		ctx.UserValue("minigame").(string),
+		ctx.UserValue("format").(string),
+		ctx.UserValue("map").(string),
+		ctx.UserValue("version").(string),
+		mapWorldFileBytes,
+		mapConfigFileBytes,
+	)
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writePublish(ctx, response)
+}
+
+func (h *Handler) PaperPublishHandler(ctx *fasthttp.RequestCtx) {
+	if !h.authorizePublish(ctx) {
+		return
+	}
+
+	paperFileBytes, err := uploadedFile(ctx, "file")
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	response, err := h.services.PublishPaper(ctx.UserValue("version").(string), paperFileBytes)
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writePublish(ctx, response)
+}
+
+func (h *Handler) VelocityPublishHandler(ctx *fasthttp.RequestCtx) {
+	if !h.authorizePublish(ctx) {
+		return
+	}
+
+	velocityFileBytes, err := uploadedFile(ctx, "file")
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	response, err := h.services.PublishVelocity(ctx.UserValue("version").(string), velocityFileBytes)
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writePublish(ctx, response)
+}
+
+func (h *Handler) authorizePublish(ctx *fasthttp.RequestCtx) bool {
+	token := strings.TrimPrefix(string(ctx.Request.Header.Peek("Authorization")), "Bearer ")
+	if h.config.PublishToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.config.PublishToken)) != 1 {
+		ctx.Response.Header.Set("WWW-Authenticate", "Bearer")
+		h.writeError(ctx, services.NewError(models.ErrorUnauthorized, "invalid publish token", nil))
+		return false
+	}
+
+	return true
+}
+
+func (h *Handler) writePublish(ctx *fasthttp.RequestCtx, publish *models.PublishResponse) {
+	response, err := json.Marshal(publish)
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	ctx.SetStatusCode(201)
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
+
+func uploadedFile(ctx *fasthttp.RequestCtx, field string) ([]byte, error) {
+	if !strings.HasPrefix(string(ctx.Request.Header.ContentType()), "multipart/form-data") {
+		if field != "file" {
+			return nil, services.NewError(models.ErrorInvalidRequest, "multipart form with "+field+" field expected", nil)
+		}
+		if len(ctx.PostBody()) == 0 {
+			return nil, services.NewError(models.ErrorInvalidRequest, "request body is empty", nil)
+		}
+		return ctx.PostBody(), nil
+	}
+
+	fileHeader, err := ctx.FormFile(field)
+	if err != nil {
+		return nil, services.NewError(models.ErrorInvalidRequest, "missing "+field+" file", err)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ioutil.ReadAll(file)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/valyala/fasthttp"
+)
+
+func (h *Handler) writeServer(ctx *fasthttp.RequestCtx, server *models.Server) {
+	response, err := json.Marshal(&models.ServerResponse{
+		Success: true,
+		Server:  *server,
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
+
+func (h *Handler) writeServers(ctx *fasthttp.RequestCtx, servers []models.Server) {
+	response, err := json.Marshal(&models.ServersResponse{
+		Success: true,
+		Servers: servers,
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/valyala/fasthttp"
+)
+
+func (h *Handler) StatusHandler(ctx *fasthttp.RequestCtx) {
+	ready, syncs := h.services.GetStatus()
+
+	response, err := json.Marshal(&models.StatusResponse{
+		Success:   true,
+		Ready:     ready,
+		Syncs:     syncs,
+		Upstreams: h.services.GetUpstreams(),
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
+
+func (h *Handler) ReadyHandler(ctx *fasthttp.RequestCtx) {
+	ready, syncs := h.services.GetStatus()
+
+	response, err := json.Marshal(&models.StatusResponse{
+		Success:   ready,
+		Ready:     ready,
+		Syncs:     syncs,
+		Upstreams: h.services.GetUpstreams(),
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	if !ready {
+		ctx.SetStatusCode(503)
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/valyala/fasthttp"
+)
+
+func (h *Handler) ProcessesHandler(ctx *fasthttp.RequestCtx) {
+	response, err := json.Marshal(&models.ProcessesResponse{
+		Success:   true,
+		Processes: h.services.GetProcesses(),
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
+
+func (h *Handler) ProcessHandler(ctx *fasthttp.RequestCtx) {
+	process, err := h.services.GetProcess(ctx.UserValue("type").(string), ctx.UserValue("name").(string))
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeProcess(ctx, process)
+}
+
+func (h *Handler) ServerStartHandler(ctx *fasthttp.RequestCtx) {
+	process, err := h.services.StartServer(ctx.UserValue("type").(string), ctx.UserValue("name").(string))
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeProcess(ctx, process)
+}
+
+func (h *Handler) ServerStopHandler(ctx *fasthttp.RequestCtx) {
+	process, err := h.services.StopServer(ctx.UserValue("type").(string), ctx.UserValue("name").(string))
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeProcess(ctx, process)
+}
+
+func (h *Handler) ServerRestartHandler(ctx *fasthttp.RequestCtx) {
+	process, err := h.services.RestartServer(ctx.UserValue("type").(string), ctx.UserValue("name").(string))
+	if err != nil {
+		h.writeError(ctx, err)
+		return
+	}
+
+	h.writeProcess(ctx, process)
+}
+
+func (h *Handler) writeProcess(ctx *fasthttp.RequestCtx, process *models.Process) {
+	response, err := json.Marshal(&models.ProcessResponse{
+		Success: true,
+		Process: *process,
+	})
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+
+	_, err = ctx.WriteString(string(response))
+	if err != nil {
+		ctx.Error(err.Error(), 500)
+		return
+	}
+}
@@ -7,17 +7,19 @@ import (
 )
 
 func (h *Handler) VelocityUpdateHandler(ctx *fasthttp.RequestCtx) {
-	if err := h.services.UpdateVelocity(); err != nil {
-		response, err := json.Marshal(&models.Error{
-			Success: false,
-			Message: err.Error(),
-		})
+	if ctx.QueryArgs().GetBool("async") {
+		job, err := h.services.StartJob("velocity", h.services.UpdateVelocity)
 		if err != nil {
-			ctx.Error(err.Error(), 500)
+			h.writeError(ctx, err)
 			return
 		}
 
-		ctx.Error(string(response), 500)
+		h.writeJob(ctx, job)
+		return
+	}
+
+	if _, err := h.services.RunJob("velocity", h.services.UpdateVelocity); err != nil {
+		h.writeError(ctx, err)
 		return
 	}
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"github.com/mineleaguedev/luximo/handlers"
 	"github.com/mineleaguedev/luximo/models"
 	"github.com/mineleaguedev/luximo/services"
@@ -8,6 +9,11 @@ import (
 	"github.com/valyala/fasthttp"
 	"log"
 	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
 )
 
 func main() {
@@ -18,6 +24,13 @@ func main() {
 		log.Fatalf("Error reading config: %s", err.Error())
 	}
 
+	viper.SetDefault("jobs_path", "jobs/")
+	viper.SetDefault("staging_path", "staging/")
+	viper.SetDefault("cache_path", "cache/")
+	viper.SetDefault("registry_path", "registry/")
+	viper.SetDefault("blobs_path", "blobs/")
+	viper.SetDefault("proxy_servers_path", "proxy/")
+
 	paths := models.Paths{
 		Path: viper.GetString("path"),
 	}
@@ -25,10 +38,16 @@ func main() {
 	paths.PaperPath = paths.Path + viper.GetString("paper_path")
 	paths.PluginsPath = paths.Path + viper.GetString("plugins_path")
 	paths.MapsPath = paths.Path + viper.GetString("maps_path")
+	paths.JobsPath = paths.Path + viper.GetString("jobs_path")
+	paths.StagingPath = paths.Path + viper.GetString("staging_path")
+	paths.CachePath = paths.Path + viper.GetString("cache_path")
+	paths.RegistryPath = paths.Path + viper.GetString("registry_path")
+	paths.BlobsPath = paths.Path + viper.GetString("blobs_path")
 	paths.ServersPath = paths.Path + viper.GetString("servers_path")
 	paths.LobbyServersPath = paths.ServersPath + viper.GetString("lobby_servers_path")
 	paths.MiniServersPath = paths.ServersPath + viper.GetString("mini_servers_path")
 	paths.MegaServersPath = paths.ServersPath + viper.GetString("mega_servers_path")
+	paths.ProxyServersPath = paths.ServersPath + viper.GetString("proxy_servers_path")
 	if err := os.MkdirAll(paths.VelocityPath, 0755); err != nil {
 		log.Fatalf("Error creating velocity folder: %s", err.Error())
 	}
@@ -41,6 +60,21 @@ func main() {
 	if err := os.MkdirAll(paths.MapsPath, 0755); err != nil {
 		log.Fatalf("Error creating maps folder: %s", err.Error())
 	}
+	if err := os.MkdirAll(paths.JobsPath, 0755); err != nil {
+		log.Fatalf("Error creating jobs folder: %s", err.Error())
+	}
+	if err := os.MkdirAll(paths.StagingPath, 0755); err != nil {
+		log.Fatalf("Error creating staging folder: %s", err.Error())
+	}
+	if err := os.MkdirAll(paths.CachePath, 0755); err != nil {
+		log.Fatalf("Error creating cache folder: %s", err.Error())
+	}
+	if err := os.MkdirAll(paths.RegistryPath, 0755); err != nil {
+		log.Fatalf("Error creating registry folder: %s", err.Error())
+	}
+	if err := os.MkdirAll(paths.BlobsPath, 0755); err != nil {
+		log.Fatalf("Error creating blobs folder: %s", err.Error())
+	}
 	if err := os.MkdirAll(paths.LobbyServersPath, 0755); err != nil {
 		log.Fatalf("Error creating lobby servers folder: %s", err.Error())
 	}
@@ -50,10 +84,234 @@ func main() {
 	if err := os.MkdirAll(paths.MegaServersPath, 0755); err != nil {
 		log.Fatalf("Error creating mega servers folder: %s", err.Error())
 	}
+	if err := os.MkdirAll(paths.ProxyServersPath, 0755); err != nil {
+		log.Fatalf("Error creating proxy servers folder: %s", err.Error())
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	viper.SetDefault("address", ":8080")
+	viper.SetDefault("jobs_history", 100)
+	viper.SetDefault("shutdown_timeout", "30s")
+	viper.SetDefault("upstream_connect_timeout", "10s")
+	viper.SetDefault("upstream_read_timeout", "30s")
+	viper.SetDefault("upstream_max_idle_conns", 16)
+	viper.SetDefault("offline_fallback", true)
+	viper.SetDefault("upstreams", []string{"https://api.mineleague.ru"})
+	viper.SetDefault("upstream_cooldown", "1m")
+	viper.SetDefault("mirror", false)
+	viper.SetDefault("registry", false)
+	viper.SetDefault("max_upload_size", "1GB")
+	viper.SetDefault("disk_reserve", "1GB")
+	viper.SetDefault("gc_interval", "24h")
+	viper.SetDefault("sync_interval", "0s")
+	viper.SetDefault("mini.map_config", "plugins/MiniGame/map.yml")
+	viper.SetDefault("proxy.backend_host", "127.0.0.1")
+	viper.SetDefault("ports.lobby", "25566-25999")
+	viper.SetDefault("ports.mini", "30000-31999")
+	viper.SetDefault("ports.mega", "35000-35099")
+	viper.SetDefault("ports.proxy", "25565")
+	viper.SetDefault("ports.rcon", "40000-42999")
+	viper.SetDefault("ports.query", "43000-45999")
+	viper.SetDefault("supervisor.java", "java")
+	viper.SetDefault("supervisor.paper_jar", "paper.jar")
+	viper.SetDefault("supervisor.velocity_jar", "velocity.jar")
+	viper.SetDefault("supervisor.log_max_size", "10MB")
+	viper.SetDefault("supervisor.log_max_files", 5)
+	viper.SetDefault("supervisor.restart_policy", map[string]string{
+		models.ServerLobby: models.RestartAlways,
+		models.ServerMini:  models.RestartNever,
+		models.ServerMega:  models.RestartAlways,
+		models.ServerProxy: models.RestartAlways,
+	})
+	viper.SetDefault("supervisor.restart_backoff", "1s")
+	viper.SetDefault("supervisor.restart_max_backoff", "1m")
+	viper.SetDefault("supervisor.restart_limit", 5)
+	viper.SetDefault("supervisor.restart_reset", "10m")
+	viper.SetDefault("supervisor.stop_timeout", "60s")
+	viper.SetDefault("supervisor.kill_timeout", "10s")
+	viper.SetDefault("supervisor.ready_patterns", map[string]string{
+		"paper":    `Done \([0-9.,]+s\)!`,
+		"velocity": `Listening on`,
+	})
+	bandwidth := models.Bandwidth{
+		Global:      int64(viper.GetSizeInBytes("bandwidth_limit")),
+		PerDownload: int64(viper.GetSizeInBytes("bandwidth_per_download")),
+		Scheduled:   int64(viper.GetSizeInBytes("bandwidth_scheduled")),
+	}
+	config := models.Config{
+		JobsHistory:            viper.GetInt("jobs_history"),
+		ShutdownTimeout:        viper.GetDuration("shutdown_timeout"),
+		UserAgent:              viper.GetString("user_agent"),
+		UpstreamConnectTimeout: viper.GetDuration("upstream_connect_timeout"),
+		UpstreamReadTimeout:    viper.GetDuration("upstream_read_timeout"),
+		UpstreamMaxIdleConns:   viper.GetInt("upstream_max_idle_conns"),
+		OfflineFallback:        viper.GetBool("offline_fallback"),
+		UpstreamCooldown:       viper.GetDuration("upstream_cooldown"),
+		UpstreamProxy:          viper.GetString("upstream_proxy"),
+		UpstreamCAFile:         viper.GetString("upstream_ca_file"),
+		UpstreamClientCert:     viper.GetString("upstream_client_cert"),
+		UpstreamClientKey:      viper.GetString("upstream_client_key"),
+		Mirror:                 viper.GetBool("mirror"),
+		Registry:               viper.GetBool("registry"),
+		PublishToken:           viper.GetString("publish_token"),
+		MaxUploadSize:          int(viper.GetSizeInBytes("max_upload_size")),
+		DiskReserve:            int64(viper.GetSizeInBytes("disk_reserve")),
+		Bandwidth:              bandwidth,
+		SyncInterval:           viper.GetDuration("sync_interval"),
+		GCInterval:             viper.GetDuration("gc_interval"),
+		Retention:              make(map[string]models.Retention),
+		Ports:                  make(map[string]models.PortRange),
+		Lobby: models.ServerConfig{
+			Plugins:    viper.GetStringSlice("lobby.plugins"),
+			World:      viper.GetString("lobby.world"),
+			Properties: viper.GetStringMapString("lobby.properties"),
+		},
+		Mini: models.ServerConfig{
+			Plugins:    viper.GetStringSlice("mini.plugins"),
+			Properties: viper.GetStringMapString("mini.properties"),
+			Minigames:  viper.GetStringMapStringSlice("mini.minigames"),
+			MapConfig:  viper.GetString("mini.map_config"),
+		},
+		Mega: models.ServerConfig{
+			Plugins:    viper.GetStringSlice("mega.plugins"),
+			World:      viper.GetString("mega.world"),
+			Properties: viper.GetStringMapString("mega.properties"),
+		},
+		Proxy: models.ServerConfig{
+			Plugins:     viper.GetStringSlice("proxy.plugins"),
+			Properties:  viper.GetStringMapString("proxy.properties"),
+			BackendHost: viper.GetString("proxy.backend_host"),
+			ForcedHosts: viper.GetStringMapStringSlice("proxy.forced_hosts"),
+		},
+		Supervisor: models.SupervisorConfig{
+			Java:              viper.GetString("supervisor.java"),
+			JavaArgs:          viper.GetStringSlice("supervisor.java_args"),
+			PaperJar:          viper.GetString("supervisor.paper_jar"),
+			VelocityJar:       viper.GetString("supervisor.velocity_jar"),
+			LogMaxSize:        int64(viper.GetSizeInBytes("supervisor.log_max_size")),
+			LogMaxFiles:       viper.GetInt("supervisor.log_max_files"),
+			RestartPolicy:     viper.GetStringMapString("supervisor.restart_policy"),
+			RestartBackoff:    viper.GetDuration("supervisor.restart_backoff"),
+			RestartMaxBackoff: viper.GetDuration("supervisor.restart_max_backoff"),
+			RestartLimit:      viper.GetInt("supervisor.restart_limit"),
+			RestartReset:      viper.GetDuration("supervisor.restart_reset"),
+			ReadyPatterns:     viper.GetStringMapString("supervisor.ready_patterns"),
+			StopTimeout:       viper.GetDuration("supervisor.stop_timeout"),
+			KillTimeout:       viper.GetDuration("supervisor.kill_timeout"),
+		},
+	}
+	for _, kind := range []string{"plugin", "map", "paper", "velocity"} {
+		viper.SetDefault("retention."+kind+".keep_versions", 3)
+		config.Retention[kind] = models.Retention{
+			KeepVersions: viper.GetInt("retention." + kind + ".keep_versions"),
+			KeepDays:     viper.GetInt("retention." + kind + ".keep_days"),
+			MaxBytes:     int64(viper.GetSizeInBytes("retention." + kind + ".max_bytes")),
+		}
+	}
+	if err := viper.UnmarshalKey("upstreams", &config.Upstreams, viper.DecodeHook(upstreamURLHook)); err != nil {
+		log.Fatalf("Error reading upstreams: %s", err.Error())
+	}
+	for _, kind := range []string{models.ServerLobby, models.ServerMini, models.ServerMega, models.ServerProxy, "rcon", "query"} {
+		var portRange models.PortRange
+		if err := viper.UnmarshalKey("ports."+kind, &portRange, viper.DecodeHook(portRangeHook)); err != nil {
+			log.Fatalf("Error reading %s ports: %s", kind, err.Error())
+		}
+		config.Ports[kind] = portRange
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = "luximo (" + hostname + ")"
+	}
+	if config.Registry && config.PublishToken == "" {
+		log.Printf("Registry mode is enabled without publish_token, publishing is disabled")
+	}
 
-	service := services.NewService(paths)
-	handler := handlers.NewHandler(service)
+	service, err := services.NewService(paths, config)
+	if err != nil {
+		log.Fatalf("Error creating services: %s", err.Error())
+	}
+	handler := handlers.NewHandler(service, config)
 
 	r := handler.InitRoutes()
-	log.Fatal(fasthttp.ListenAndServe(":8080", r.Handler))
+	server := &fasthttp.Server{
+		Handler:            r.Handler,
+		MaxRequestBodySize: config.MaxUploadSize,
+	}
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- server.ListenAndServe(viper.GetString("address"))
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErrors:
+		log.Fatal(err)
+	case sig := <-signals:
+		log.Printf("Received %s, shutting down", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	serverStopped := make(chan struct{})
+	go func() {
+		if err := server.Shutdown(); err != nil {
+			log.Printf("Error shutting down server: %s", err.Error())
+		}
+		close(serverStopped)
+	}()
+
+	if err := service.Shutdown(ctx); err != nil {
+		log.Printf("Error finishing jobs: %s", err.Error())
+	}
+
+	select {
+	case <-serverStopped:
+	case <-ctx.Done():
+		log.Printf("Grace period expired, closing remaining connections")
+	}
+}
+
+func upstreamURLHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() == reflect.String && to == reflect.TypeOf(models.UpstreamConfig{}) {
+		return models.UpstreamConfig{URL: data.(string)}, nil
+	}
+
+	return data, nil
+}
+
+func portRangeHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(models.PortRange{}) {
+		return data, nil
+	}
+
+	var bounds []string
+	switch from.Kind() {
+	case reflect.String:
+		bounds = strings.SplitN(data.(string), "-", 2)
+	case reflect.Int:
+		bounds = []string{strconv.Itoa(data.(int))}
+	default:
+		return data, nil
+	}
+	if len(bounds) == 1 {
+		bounds = append(bounds, bounds[0])
+	}
+
+	portFrom, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return nil, err
+	}
+	portTo, err := strconv.Atoi(strings.TrimSpace(bounds[1]))
+	if err != nil {
+		return nil, err
+	}
+
+	return models.PortRange{From: portFrom, To: portTo}, nil
 }
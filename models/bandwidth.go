@@ -0,0 +1,12 @@
+package models
+
+type Bandwidth struct {
+	Global      int64 `json:"global"`
+	PerDownload int64 `json:"perDownload"`
+	Scheduled   int64 `json:"scheduled"`
+}
+
+type BandwidthResponse struct {
+	Success   bool      `json:"success"`
+	Bandwidth Bandwidth `json:"bandwidth"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+type Config struct {
+	JobsHistory            int
+	ShutdownTimeout        time.Duration
+	UserAgent              string
+	UpstreamConnectTimeout time.Duration
+	UpstreamReadTimeout    time.Duration
+	UpstreamMaxIdleConns   int
+	Upstreams              []UpstreamConfig
+	UpstreamCooldown       time.Duration
+	UpstreamProxy          string
+	UpstreamCAFile         string
+	UpstreamClientCert     string
+	UpstreamClientKey      string
+	Mirror                 bool
+	Registry               bool
+	PublishToken           string
+	MaxUploadSize          int
+	OfflineFallback        bool
+	DiskReserve            int64
+	Bandwidth              Bandwidth
+	SyncInterval           time.Duration
+	GCInterval             time.Duration
+	Retention              map[string]Retention
+	Lobby                  ServerConfig
+	Mini                   ServerConfig
+	Mega                   ServerConfig
+	Proxy                  ServerConfig
+	Supervisor             SupervisorConfig
+	Ports                  map[string]PortRange
+}
+
+type UpstreamConfig struct {
+	URL  string       `mapstructure:"url"`
+	Auth UpstreamAuth `mapstructure:"auth"`
+}
+
+type UpstreamAuth struct {
+	Type             string   `mapstructure:"type"`
+	Token            string   `mapstructure:"token"`
+	TokenFile        string   `mapstructure:"token_file"`
+	TokenEnv         string   `mapstructure:"token_env"`
+	TokenURL         string   `mapstructure:"token_url"`
+	ClientID         string   `mapstructure:"client_id"`
+	ClientSecret     string   `mapstructure:"client_secret"`
+	ClientSecretFile string   `mapstructure:"client_secret_file"`
+	ClientSecretEnv  string   `mapstructure:"client_secret_env"`
+	Scopes           []string `mapstructure:"scopes"`
+}
+
+type Retention struct {
+	KeepVersions int
+	KeepDays     int
+	MaxBytes     int64
+}
+
+type SupervisorConfig struct {
+	Java              string
+	JavaArgs          []string
+	PaperJar          string
+	VelocityJar       string
+	LogMaxSize        int64
+	LogMaxFiles       int
+	RestartPolicy     map[string]string
+	RestartBackoff    time.Duration
+	RestartMaxBackoff time.Duration
+	RestartLimit      int
+	RestartReset      time.Duration
+	ReadyPatterns     map[string]string
+	StopTimeout       time.Duration
+	KillTimeout       time.Duration
+}
+
+type ServerConfig struct {
+	Plugins     []string
+	World       string
+	Properties  map[string]string
+	Minigames   map[string][]string
+	MapConfig   string
+	BackendHost string
+	ForcedHosts map[string][]string
+}
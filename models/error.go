@@ -1,6 +1,22 @@
 package models
 
+const (
+	ErrorUpstreamUnreachable = "upstream_unreachable"
+	ErrorUpstreamRejected    = "upstream_rejected"
+	ErrorChecksumMismatch    = "checksum_mismatch"
+	ErrorNotFound            = "not_found"
+	ErrorConflict            = "conflict"
+	ErrorDiskFull            = "disk_full"
+	ErrorInvalidConfig       = "invalid_config"
+	ErrorInvalidRequest      = "invalid_request"
+	ErrorUnauthorized        = "unauthorized"
+	ErrorShuttingDown        = "shutting_down"
+	ErrorCanceled            = "canceled"
+	ErrorInternal            = "internal"
+)
+
 type Error struct {
 	Success bool   `json:"success"`
+	Code    string `json:"code"`
 	Message string `json:"message"`
 }
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	EventSyncStarted      = "syncStarted"
+	EventListingFetched   = "listingFetched"
+	EventListingStale     = "listingStale"
+	EventDownloadStarted  = "downloadStarted"
+	EventDownloadProgress = "downloadProgress"
+	EventDownloadFinished = "downloadFinished"
+	EventInstalled        = "installed"
+	EventRemoved          = "removed"
+	EventSyncCompleted    = "syncCompleted"
+)
+
+type Event struct {
+	Type   string     `json:"type"`
+	JobID  string     `json:"jobId,omitempty"`
+	Kind   string     `json:"kind,omitempty"`
+	Name   string     `json:"name,omitempty"`
+	Items  int        `json:"items,omitempty"`
+	Cached bool       `json:"cached,omitempty"`
+	Bytes  int64      `json:"bytes,omitempty"`
+	Total  int64      `json:"total,omitempty"`
+	State  string     `json:"state,omitempty"`
+	Since  *time.Time `json:"since,omitempty"`
+	Error  string     `json:"error,omitempty"`
+	Code   string     `json:"code,omitempty"`
+	Time   time.Time  `json:"time"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+type Job struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	State      string     `json:"state"`
+	Progress   Progress   `json:"progress"`
+	Report     Report     `json:"report"`
+	Error      string     `json:"error,omitempty"`
+	ErrorCode  string     `json:"errorCode,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+type Progress struct {
+	ItemsDone  int   `json:"itemsDone"`
+	ItemsTotal int   `json:"itemsTotal"`
+	BytesDone  int64 `json:"bytesDone"`
+	BytesTotal int64 `json:"bytesTotal"`
+}
+
+type Report struct {
+	Installed  []string   `json:"installed"`
+	Removed    []string   `json:"removed"`
+	StaleSince *time.Time `json:"staleSince,omitempty"`
+}
+
+type JobResponse struct {
+	Success bool `json:"success"`
+	Job     Job  `json:"job"`
+}
+
+type JobsResponse struct {
+	Success bool  `json:"success"`
+	Jobs    []Job `json:"jobs"`
+}
@@ -11,11 +11,18 @@ type Format struct {
 }
 
 type Map struct {
-	Name        string   `json:"name"`
-	Versions    []string `json:"versions"`
-	LastVersion string   `json:"lastVersion"`
-	HasWorld    bool     `json:"hasWorld"`
-	HasConfig   bool     `json:"hasConfig"`
+	Name        string                  `json:"name"`
+	Versions    []string                `json:"versions"`
+	LastVersion string                  `json:"lastVersion"`
+	HasWorld    bool                    `json:"hasWorld"`
+	HasConfig   bool                    `json:"hasConfig"`
+	Checksums   map[string]MapChecksums `json:"checksums,omitempty"`
+	Sizes       map[string]int64        `json:"sizes,omitempty"`
+}
+
+type MapChecksums struct {
+	World  string `json:"world"`
+	Config string `json:"config"`
 }
 
 type MapsResponse struct {
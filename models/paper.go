@@ -1,7 +1,9 @@
 package models
 
 type PaperResponse struct {
-	Success     bool     `json:"success"`
-	Versions    []string `json:"versions"`
-	LastVersion string   `json:"lastVersion"`
+	Success     bool              `json:"success"`
+	Versions    []string          `json:"versions"`
+	LastVersion string            `json:"lastVersion"`
+	Checksums   map[string]string `json:"checksums,omitempty"`
+	Sizes       map[string]int64  `json:"sizes,omitempty"`
 }
@@ -6,8 +6,14 @@ type Paths struct {
 	PaperPath        string
 	PluginsPath      string
 	MapsPath         string
+	JobsPath         string
+	StagingPath      string
+	CachePath        string
+	RegistryPath     string
+	BlobsPath        string
 	ServersPath      string
 	LobbyServersPath string
 	MiniServersPath  string
 	MegaServersPath  string
+	ProxyServersPath string
 }
@@ -1,9 +1,11 @@
 package models
 
 type Plugin struct {
-	Name        string   `json:"name"`
-	Versions    []string `json:"versions"`
-	LastVersion string   `json:"lastVersion"`
+	Name        string            `json:"name"`
+	Versions    []string          `json:"versions"`
+	LastVersion string            `json:"lastVersion"`
+	Checksums   map[string]string `json:"checksums,omitempty"`
+	Sizes       map[string]int64  `json:"sizes,omitempty"`
 }
 
 type PluginsResponse struct {
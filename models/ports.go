@@ -0,0 +1,19 @@
+package models
+
+type PortRange struct {
+	From int
+	To   int
+}
+
+type PortAssignment struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Server int    `json:"server"`
+	RCON   int    `json:"rcon,omitempty"`
+	Query  int    `json:"query,omitempty"`
+}
+
+type PortsResponse struct {
+	Success bool             `json:"success"`
+	Ports   []PortAssignment `json:"ports"`
+}
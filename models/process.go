@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+const (
+	ProcessStarting = "starting"
+	ProcessRunning  = "running"
+	ProcessStopping = "stopping"
+	ProcessStopped  = "stopped"
+	ProcessCrashed  = "crashed"
+	ProcessFailed   = "failed"
+)
+
+const (
+	StopCommand = "command"
+	StopSigterm = "sigterm"
+	StopSigkill = "sigkill"
+)
+
+const (
+	RestartAlways    = "always"
+	RestartOnFailure = "on-failure"
+	RestartNever     = "never"
+)
+
+type Process struct {
+	Name      string     `json:"name"`
+	Type      string     `json:"type"`
+	State     string     `json:"state"`
+	PID       int        `json:"pid,omitempty"`
+	ExitCode  *int       `json:"exitCode,omitempty"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	StoppedAt *time.Time `json:"stoppedAt,omitempty"`
+	StoppedBy string     `json:"stoppedBy,omitempty"`
+	Ready     bool       `json:"ready"`
+	ReadyAt   *time.Time `json:"readyAt,omitempty"`
+	Restarts  int        `json:"restarts"`
+	RestartAt *time.Time `json:"restartAt,omitempty"`
+	Crashes   []Crash    `json:"crashes,omitempty"`
+	Output    []string   `json:"output,omitempty"`
+}
+
+type Crash struct {
+	ExitCode int       `json:"exitCode"`
+	At       time.Time `json:"at"`
+	Output   []string  `json:"output"`
+}
+
+type ProcessResponse struct {
+	Success bool    `json:"success"`
+	Process Process `json:"process"`
+}
+
+type ProcessesResponse struct {
+	Success   bool      `json:"success"`
+	Processes []Process `json:"processes"`
+}
@@ -0,0 +1,9 @@
+package models
+
+type PublishResponse struct {
+	Success   bool              `json:"success"`
+	Kind      string            `json:"kind"`
+	Name      string            `json:"name,omitempty"`
+	Version   string            `json:"version"`
+	Checksums map[string]string `json:"checksums"`
+}
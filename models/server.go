@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+const (
+	ServerLobby = "lobby"
+	ServerMini  = "mini"
+	ServerMega  = "mega"
+	ServerProxy = "proxy"
+)
+
+type Server struct {
+	Name      string     `json:"name"`
+	Type      string     `json:"type"`
+	Paper     string     `json:"paper,omitempty"`
+	Velocity  string     `json:"velocity,omitempty"`
+	Plugins   []string   `json:"plugins"`
+	Map       string     `json:"map,omitempty"`
+	Port      int        `json:"port"`
+	RCONPort  int        `json:"rconPort,omitempty"`
+	QueryPort int        `json:"queryPort,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+type CreateServerRequest struct {
+	Name     string `json:"name"`
+	Port     int    `json:"port"`
+	Minigame string `json:"minigame,omitempty"`
+	Format   string `json:"format,omitempty"`
+	Map      string `json:"map,omitempty"`
+}
+
+type ServerResponse struct {
+	Success bool   `json:"success"`
+	Server  Server `json:"server"`
+}
+
+type ServersResponse struct {
+	Success bool     `json:"success"`
+	Servers []Server `json:"servers"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+const (
+	SyncOK     = "ok"
+	SyncStale  = "stale"
+	SyncFailed = "failed"
+)
+
+type SyncStatus struct {
+	Kind       string     `json:"kind"`
+	State      string     `json:"state"`
+	StaleSince *time.Time `json:"staleSince,omitempty"`
+	LastJob    string     `json:"lastJob"`
+	LastSync   *time.Time `json:"lastSync,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	ErrorCode  string     `json:"errorCode,omitempty"`
+}
+
+type Upstream struct {
+	URL            string     `json:"url"`
+	Healthy        bool       `json:"healthy"`
+	Failures       int        `json:"failures"`
+	UnhealthyUntil *time.Time `json:"unhealthyUntil,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+}
+
+type StatusResponse struct {
+	Success   bool         `json:"success"`
+	Ready     bool         `json:"ready"`
+	Syncs     []SyncStatus `json:"syncs"`
+	Upstreams []Upstream   `json:"upstreams"`
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"github.com/mineleaguedev/luximo/models"
+	"github.com/nwaples/rardecode"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func extractRar(archive, target string) error {
+	reader, err := rardecode.OpenReader(archive, "")
+	if err != nil {
+		return NewError(models.ErrorInvalidRequest, "error opening "+filepath.Base(archive), err)
+	}
+	defer reader.Close()
+
+	target = filepath.Clean(target)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return NewError(models.ErrorInvalidRequest, "error reading "+filepath.Base(archive), err)
+		}
+
+		path := filepath.Join(target, filepath.FromSlash(header.Name))
+		if path != target && !strings.HasPrefix(path, target+string(filepath.Separator)) {
+			return NewError(models.ErrorInvalidRequest, "archive entry "+header.Name+" escapes target folder", nil)
+		}
+
+		if header.IsDir {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+
+		mode := header.Mode().Perm()
+		if mode == 0 {
+			mode = 0644
+		}
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(file, reader); err != nil {
+			file.Close()
+			return err
+		}
+
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+}
+
+func extractWorld(archive, target string) error {
+	if err := extractRar(archive, target); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(target, "level.dat")); err == nil {
+		return nil
+	}
+
+	entries, err := os.ReadDir(target)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return nil
+	}
+
+	nested := filepath.Clean(target) + ".nested"
+	if err := os.Rename(filepath.Join(target, entries[0].Name()), nested); err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil {
+		return err
+	}
+
+	return os.Rename(nested, target)
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type upstreamAuth interface {
+	authorize(ctx context.Context, req *http.Request) error
+	invalidate() bool
+}
+
+func newUpstreamAuth(auth models.UpstreamAuth, client *http.Client) (upstreamAuth, error) {
+	switch auth.Type {
+	case "", "none":
+		return nil, nil
+	case "bearer":
+		token, err := resolveSecret("token", auth.Token, auth.TokenFile, auth.TokenEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		return &bearerAuth{token: token}, nil
+	case "oauth2":
+		if auth.TokenURL == "" || auth.ClientID == "" {
+			return nil, NewError(models.ErrorInvalidConfig, "oauth2 upstream auth requires token_url and client_id", nil)
+		}
+
+		clientSecret, err := resolveSecret("client_secret", auth.ClientSecret, auth.ClientSecretFile, auth.ClientSecretEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		return &oauth2Auth{
+			client:       client,
+			tokenURL:     auth.TokenURL,
+			clientID:     auth.ClientID,
+			clientSecret: clientSecret,
+			scopes:       auth.Scopes,
+		}, nil
+	}
+
+	return nil, NewError(models.ErrorInvalidConfig, "unknown upstream auth type "+auth.Type, nil)
+}
+
+func resolveSecret(name, value, file, env string) (string, error) {
+	switch {
+	case file != "":
+		secretBytes, err := os.ReadFile(file)
+		if err != nil {
+			return "", NewError(models.ErrorInvalidConfig, "error reading "+name+" file", err)
+		}
+		value = strings.TrimSpace(string(secretBytes))
+	case env != "":
+		value = os.Getenv(env)
+	}
+
+	if value == "" {
+		return "", NewError(models.ErrorInvalidConfig, "upstream auth "+name+" is empty", nil)
+	}
+
+	return value, nil
+}
+
+type bearerAuth struct {
+	token string
+}
+
+func (a *bearerAuth) authorize(ctx context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	return nil
+}
+
+func (a *bearerAuth) invalidate() bool {
+	return false
+}
+
+type oauth2Auth struct {
+	client       *http.Client
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	mutex        sync.Mutex
+	token        string
+	expiresAt    time.Time
+}
+
+type oauth2Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (a *oauth2Auth) authorize(ctx context.Context, req *http.Request) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.token == "" || !time.Now().Before(a.expiresAt) {
+		if err := a.refresh(ctx); err != nil {
+			return err
+		}
+	}
+
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	return nil
+}
+
+func (a *oauth2Auth) invalidate() bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.token = ""
+	return true
+}
+
+func (a *oauth2Auth) refresh(ctx context.Context) error {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.scopes) > 0 {
+		form.Set("scope", strings.Join(a.scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return NewError(models.ErrorInvalidConfig, "invalid oauth2 token_url", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(a.clientID), url.QueryEscape(a.clientSecret))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return NewError(models.ErrorUpstreamUnreachable, "error requesting oauth2 token", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return upstreamStatusError("error requesting oauth2 token", resp)
+	}
+
+	var token oauth2Token
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return NewError(models.ErrorUpstreamRejected, "invalid oauth2 token response", err)
+	}
+	if token.AccessToken == "" {
+		return NewError(models.ErrorUpstreamRejected, "oauth2 token response has no access_token", nil)
+	}
+
+	a.token = token.AccessToken
+	lifetime := time.Hour
+	if token.ExpiresIn > 0 {
+		lifetime = time.Duration(token.ExpiresIn) * time.Second
+	}
+	a.expiresAt = time.Now().Add(lifetime - lifetime/10)
+
+	return nil
+}
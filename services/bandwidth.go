@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"github.com/mineleaguedev/luximo/models"
+	"io"
+	"sync"
+	"time"
+)
+
+type scheduledKey struct{}
+
+func withScheduled(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scheduledKey{}, true)
+}
+
+func isScheduled(ctx context.Context) bool {
+	scheduled, _ := ctx.Value(scheduledKey{}).(bool)
+	return scheduled
+}
+
+type BandwidthLimiter struct {
+	mutex       sync.RWMutex
+	perDownload int64
+	global      *tokenBucket
+	scheduled   *tokenBucket
+}
+
+func NewBandwidthLimiter(config models.Config) *BandwidthLimiter {
+	return &BandwidthLimiter{
+		perDownload: config.Bandwidth.PerDownload,
+		global:      newTokenBucket(config.Bandwidth.Global),
+		scheduled:   newTokenBucket(config.Bandwidth.Scheduled),
+	}
+}
+
+func (l *BandwidthLimiter) GetBandwidth() models.Bandwidth {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return models.Bandwidth{
+		Global:      l.global.getRate(),
+		PerDownload: l.perDownload,
+		Scheduled:   l.scheduled.getRate(),
+	}
+}
+
+func (l *BandwidthLimiter) SetBandwidth(bandwidth models.Bandwidth) error {
+	if bandwidth.Global < 0 || bandwidth.PerDownload < 0 || bandwidth.Scheduled < 0 {
+		return NewError(models.ErrorInvalidRequest, "bandwidth limits must not be negative", nil)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.perDownload = bandwidth.PerDownload
+	l.global.setRate(bandwidth.Global)
+	l.scheduled.setRate(bandwidth.Scheduled)
+
+	return nil
+}
+
+func (l *BandwidthLimiter) limit(ctx context.Context, body io.ReadCloser) io.ReadCloser {
+	l.mutex.RLock()
+	perDownload := l.perDownload
+	l.mutex.RUnlock()
+
+	buckets := []*tokenBucket{l.global, newTokenBucket(perDownload)}
+	if isScheduled(ctx) {
+		buckets = append(buckets, l.scheduled)
+	}
+
+	return &limitedBody{body: body, ctx: ctx, buckets: buckets}
+}
+
+type limitedBody struct {
+	body    io.ReadCloser
+	ctx     context.Context
+	buckets []*tokenBucket
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	for _, bucket := range b.buckets {
+		if chunk := bucket.chunk(); chunk > 0 && len(p) > chunk {
+			p = p[:chunk]
+		}
+	}
+
+	n, err := b.body.Read(p)
+	for _, bucket := range b.buckets {
+		if waitErr := bucket.wait(b.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   int64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int64) *tokenBucket {
+	return &tokenBucket{rate: rate, tokens: float64(rate), last: time.Now()}
+}
+
+func (b *tokenBucket) getRate() int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.rate
+}
+
+func (b *tokenBucket) setRate(rate int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.rate = rate
+	b.last = time.Now()
+	if b.tokens > float64(rate) {
+		b.tokens = float64(rate)
+	}
+}
+
+func (b *tokenBucket) chunk() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.rate <= 0 {
+		return 0
+	}
+	if b.rate < 10240 {
+		return 1024
+	}
+
+	return int(b.rate / 10)
+}
+
+func (b *tokenBucket) wait(ctx context.Context, n int) error {
+	b.mutex.Lock()
+	if b.rate <= 0 || n <= 0 {
+		b.mutex.Unlock()
+		return nil
+	}
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * float64(b.rate)
+	if b.tokens > float64(b.rate) {
+		b.tokens = float64(b.rate)
+	}
+	b.last = now
+	b.tokens -= float64(n)
+
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / float64(b.rate) * float64(time.Second))
+	}
+	b.mutex.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
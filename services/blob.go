@@ -0,0 +1,281 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+type BlobStore struct {
+	paths   models.Paths
+	reserve int64
+	mutex   sync.Mutex
+	index   map[string]blobEntry
+}
+
+type blobEntry struct {
+	Sum    string    `json:"sum"`
+	UsedAt time.Time `json:"usedAt"`
+}
+
+func NewBlobStore(paths models.Paths, config models.Config) *BlobStore {
+	s := &BlobStore{paths: paths, reserve: config.DiskReserve, index: make(map[string]blobEntry)}
+
+	if err := s.loadIndex(); err != nil {
+		log.Printf("Error loading blob index: %s", err.Error())
+	}
+
+	return s
+}
+
+func (s *BlobStore) Has(key, expected string) bool {
+	_, ok := s.find(key, expected)
+	return ok
+}
+
+func (s *BlobStore) Lookup(key, expected string) (string, bool) {
+	sum, ok := s.find(key, expected)
+	if !ok {
+		return "", false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.remember(key, sum); err != nil {
+		log.Printf("Error saving blob index: %s", err.Error())
+	}
+
+	return sum, true
+}
+
+func (s *BlobStore) Verify(name, expected string, data []byte) error {
+	if expected == "" {
+		return nil
+	}
+
+	if sum := checksum(data); sum != expected {
+		return NewError(models.ErrorChecksumMismatch, "checksum mismatch for "+name+": expected "+expected+", got "+sum, nil)
+	}
+
+	return nil
+}
+
+func (s *BlobStore) Install(key, target string, data []byte) error {
+	sum, err := s.Store(key, data)
+	if err != nil {
+		return err
+	}
+
+	return s.Link(sum, target)
+}
+
+func (s *BlobStore) Store(key string, data []byte) (string, error) {
+	sum := checksum(data)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := os.Stat(s.blobPath(sum)); os.IsNotExist(err) {
+		if err := os.MkdirAll(s.paths.BlobsPath+sum[:2], 0755); err != nil {
+			return "", err
+		}
+
+		if err := writeStaged(s.paths, s.blobPath(sum), data); err != nil {
+			return "", err
+		}
+	} else if err != nil {
+		return "", err
+	}
+
+	if err := s.remember(key, sum); err != nil {
+		return "", err
+	}
+
+	return sum, nil
+}
+
+func (s *BlobStore) Link(sum, target string) error {
+	file, err := os.CreateTemp(s.paths.StagingPath, "*.part")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	if err := os.Link(s.blobPath(sum), file.Name()); err != nil {
+		if err := copyBlob(s.blobPath(sum), file.Name()); err != nil {
+			os.Remove(file.Name())
+			return err
+		}
+	}
+
+	if err := os.Rename(file.Name(), target); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return nil
+}
+
+func (s *BlobStore) Forget(keys []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, key := range keys {
+		delete(s.index, key)
+	}
+
+	return s.saveIndex()
+}
+
+func (s *BlobStore) Prune() (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	referenced := make(map[string]bool)
+	for _, entry := range s.index {
+		referenced[entry.Sum] = true
+	}
+
+	folders, err := os.ReadDir(s.paths.BlobsPath)
+	if err != nil {
+		return 0, err
+	}
+
+	var freed int64
+	for _, folder := range folders {
+		if !folder.IsDir() {
+			continue
+		}
+
+		blobs, err := os.ReadDir(s.paths.BlobsPath + folder.Name())
+		if err != nil {
+			return freed, err
+		}
+
+		for _, blob := range blobs {
+			if referenced[blob.Name()] {
+				continue
+			}
+
+			info, err := blob.Info()
+			if err != nil {
+				return freed, err
+			}
+
+			if err := os.Remove(s.paths.BlobsPath + folder.Name() + "/" + blob.Name()); err != nil {
+				return freed, err
+			}
+			freed += info.Size()
+		}
+	}
+
+	return freed, nil
+}
+
+func (s *BlobStore) entries() map[string]blobEntry {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries := make(map[string]blobEntry, len(s.index))
+	for key, entry := range s.index {
+		entries[key] = entry
+	}
+
+	return entries
+}
+
+func (s *BlobStore) blobSize(sum string) int64 {
+	info, err := os.Stat(s.blobPath(sum))
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+}
+
+func (s *BlobStore) find(key, expected string) (string, bool) {
+	sum := expected
+	if sum == "" {
+		s.mutex.Lock()
+		sum = s.index[key].Sum
+		s.mutex.Unlock()
+	}
+
+	if len(sum) != sha256.Size*2 {
+		return "", false
+	}
+
+	if _, err := os.Stat(s.blobPath(sum)); err != nil {
+		return "", false
+	}
+
+	return sum, true
+}
+
+func (s *BlobStore) blobPath(sum string) string {
+	return s.paths.BlobsPath + sum[:2] + "/" + sum
+}
+
+func (s *BlobStore) remember(key, sum string) error {
+	s.index[key] = blobEntry{Sum: sum, UsedAt: time.Now()}
+	return s.saveIndex()
+}
+
+func (s *BlobStore) saveIndex() error {
+	indexBytes, err := json.Marshal(s.index)
+	if err != nil {
+		return err
+	}
+
+	return writeStaged(s.paths, s.paths.BlobsPath+"index.json", indexBytes)
+}
+
+func (s *BlobStore) loadIndex() error {
+	indexBytes, err := os.ReadFile(s.paths.BlobsPath + "index.json")
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var index map[string]blobEntry
+	if err := json.Unmarshal(indexBytes, &index); err != nil {
+		return err
+	}
+
+	for key, entry := range index {
+		if len(entry.Sum) == sha256.Size*2 {
+			s.index[key] = entry
+		}
+	}
+
+	return nil
+}
+
+func copyBlob(source, target string) error {
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	targetFile, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(targetFile, sourceFile); err != nil {
+		targetFile.Close()
+		return err
+	}
+
+	return targetFile.Close()
+}
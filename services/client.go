@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/mineleaguedev/luximo/models"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Client struct {
+	http        *http.Client
+	userAgent   string
+	readTimeout time.Duration
+	cooldown    time.Duration
+	bandwidth   *BandwidthLimiter
+	mutex       sync.Mutex
+	upstreams   []*upstream
+}
+
+type upstream struct {
+	url            string
+	auth           upstreamAuth
+	failures       int
+	unhealthyUntil time.Time
+	lastError      string
+}
+
+func NewClient(config models.Config, bandwidth *BandwidthLimiter) (*Client, error) {
+	proxy, err := upstreamProxy(config)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := upstreamTLS(config)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   config.UpstreamConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+
+	c := &Client{
+		http: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 proxy,
+				DialContext:           dialer.DialContext,
+				TLSClientConfig:       tlsConfig,
+				TLSHandshakeTimeout:   config.UpstreamConnectTimeout,
+				ResponseHeaderTimeout: config.UpstreamReadTimeout,
+				ExpectContinueTimeout: time.Second,
+				MaxIdleConns:          config.UpstreamMaxIdleConns,
+				MaxIdleConnsPerHost:   config.UpstreamMaxIdleConns,
+				IdleConnTimeout:       90 * time.Second,
+				ForceAttemptHTTP2:     true,
+			},
+		},
+		userAgent:   config.UserAgent,
+		readTimeout: config.UpstreamReadTimeout,
+		cooldown:    config.UpstreamCooldown,
+		bandwidth:   bandwidth,
+	}
+
+	for _, upstreamConfig := range config.Upstreams {
+		auth, err := newUpstreamAuth(upstreamConfig.Auth, c.http)
+		if err != nil {
+			return nil, err
+		}
+
+		c.upstreams = append(c.upstreams, &upstream{url: strings.TrimSuffix(upstreamConfig.URL, "/"), auth: auth})
+	}
+
+	return c, nil
+}
+
+func upstreamProxy(config models.Config) (func(*http.Request) (*url.URL, error), error) {
+	if config.UpstreamProxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := url.Parse(config.UpstreamProxy)
+	if err != nil {
+		return nil, NewError(models.ErrorInvalidConfig, "invalid upstream_proxy", err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, NewError(models.ErrorInvalidConfig, "unsupported upstream_proxy scheme "+proxyURL.Scheme, nil)
+	}
+
+	return http.ProxyURL(proxyURL), nil
+}
+
+func upstreamTLS(config models.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if config.UpstreamCAFile != "" {
+		caBytes, err := os.ReadFile(config.UpstreamCAFile)
+		if err != nil {
+			return nil, NewError(models.ErrorInvalidConfig, "error reading upstream_ca_file", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return nil, NewError(models.ErrorInvalidConfig, "no certificates found in upstream_ca_file", nil)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.UpstreamClientCert != "" || config.UpstreamClientKey != "" {
+		certificate, err := tls.LoadX509KeyPair(config.UpstreamClientCert, config.UpstreamClientKey)
+		if err != nil {
+			return nil, NewError(models.ErrorInvalidConfig, "error loading upstream client certificate", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+func (c *Client) Get(ctx context.Context, path string) (*http.Response, error) {
+	return c.Do(ctx, path, nil)
+}
+
+func (c *Client) Size(ctx context.Context, path string) (int64, error) {
+	resp, err := c.request(ctx, http.MethodHead, path, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 || resp.ContentLength < 0 {
+		return 0, nil
+	}
+
+	return resp.ContentLength, nil
+}
+
+func (c *Client) Do(ctx context.Context, path string, header http.Header) (*http.Response, error) {
+	return c.request(ctx, http.MethodGet, path, header)
+}
+
+func (c *Client) request(ctx context.Context, method, path string, header http.Header) (*http.Response, error) {
+	candidates := c.candidates()
+	if len(candidates) == 0 {
+		return nil, NewError(models.ErrorInvalidConfig, "no upstreams configured", nil)
+	}
+
+	var lastErr error
+	for i, candidate := range candidates {
+		resp, err := c.do(ctx, method, candidate, path, header)
+		if err == nil && resp.StatusCode == 401 && candidate.auth != nil && candidate.auth.invalidate() {
+			resp.Body.Close()
+			resp, err = c.do(ctx, method, candidate, path, header)
+		}
+		if err == nil && resp.StatusCode < 500 {
+			c.markHealthy(candidate)
+			return resp, nil
+		}
+
+		if err == nil {
+			c.markUnhealthy(candidate, resp.Status)
+			if i == len(candidates)-1 {
+				return resp, nil
+			}
+
+			resp.Body.Close()
+			continue
+		}
+
+		if ctx.Err() != nil || ErrorCode(err) != models.ErrorUpstreamUnreachable {
+			return nil, err
+		}
+
+		c.markUnhealthy(candidate, err.Error())
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+func (c *Client) GetUpstreams() []models.Upstream {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	upstreams := make([]models.Upstream, 0, len(c.upstreams))
+	for _, u := range c.upstreams {
+		status := models.Upstream{
+			URL:       u.url,
+			Healthy:   !time.Now().Before(u.unhealthyUntil),
+			Failures:  u.failures,
+			LastError: u.lastError,
+		}
+		if !status.Healthy {
+			unhealthyUntil := u.unhealthyUntil
+			status.UnhealthyUntil = &unhealthyUntil
+		}
+		upstreams = append(upstreams, status)
+	}
+
+	return upstreams
+}
+
+func (c *Client) candidates() []*upstream {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	var healthy []*upstream
+	for _, u := range c.upstreams {
+		if !now.Before(u.unhealthyUntil) {
+			healthy = append(healthy, u)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return append([]*upstream(nil), c.upstreams...)
+	}
+
+	return healthy
+}
+
+func (c *Client) markHealthy(u *upstream) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	u.failures = 0
+	u.unhealthyUntil = time.Time{}
+}
+
+func (c *Client) markUnhealthy(u *upstream, reason string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !time.Now().Before(u.unhealthyUntil) {
+		log.Printf("Upstream %s is unhealthy, skipping it for %s: %s", u.url, c.cooldown, reason)
+	}
+
+	u.failures++
+	u.lastError = reason
+	u.unhealthyUntil = time.Now().Add(c.cooldown)
+}
+
+func (c *Client) do(ctx context.Context, method string, u *upstream, path string, header http.Header) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.url+path, nil)
+	if err != nil {
+		cancel()
+		return nil, NewError(models.ErrorInvalidConfig, "invalid upstream URL", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	if u.auth != nil {
+		if err := u.auth.authorize(ctx, req); err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		defer cancel()
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, NewError(models.ErrorUpstreamUnreachable, "", err)
+	}
+
+	if c.readTimeout > 0 {
+		body := &idleTimeoutBody{body: resp.Body, timeout: c.readTimeout, cancel: cancel}
+		body.timer = time.AfterFunc(c.readTimeout, body.expire)
+		resp.Body = body
+	} else {
+		resp.Body = &cancelBody{body: resp.Body, cancel: cancel}
+	}
+	resp.Body = c.bandwidth.limit(ctx, resp.Body)
+
+	return resp, nil
+}
+
+type cancelBody struct {
+	body   io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Read(p []byte) (int, error) {
+	return b.body.Read(p)
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.body.Close()
+}
+
+type idleTimeoutBody struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	cancel  context.CancelFunc
+	timer   *time.Timer
+	mutex   sync.Mutex
+	expired bool
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.expired {
+		return n, NewError(models.ErrorUpstreamUnreachable, "upstream read timed out after "+b.timeout.String(), nil)
+	}
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	defer b.cancel()
+	return b.body.Close()
+}
+
+func (b *idleTimeoutBody) expire() {
+	b.mutex.Lock()
+	b.expired = true
+	b.mutex.Unlock()
+
+	b.cancel()
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+type consoleLog struct {
+	mutex    sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+	failing  bool
+}
+
+func openConsoleLog(path string, maxSize int64, maxFiles int) (*consoleLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	l := &consoleLog{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *consoleLog) Write(p []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(p)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			l.fail(err)
+		}
+	}
+	if l.file == nil {
+		if err := l.open(); err != nil {
+			l.fail(err)
+			return len(p), nil
+		}
+	}
+
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+	if err != nil {
+		l.fail(err)
+		return len(p), nil
+	}
+	l.failing = false
+
+	return len(p), nil
+}
+
+func (l *consoleLog) fail(err error) {
+	if !l.failing {
+		log.Printf("Error writing console log %s: %s", l.path, err.Error())
+	}
+	l.failing = true
+}
+
+func (l *consoleLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	return l.file.Close()
+}
+
+func (l *consoleLog) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	l.file = file
+	l.size = info.Size()
+
+	return nil
+}
+
+func (l *consoleLog) rotate() error {
+	err := l.file.Close()
+	l.file = nil
+	if err != nil {
+		return err
+	}
+
+	if l.maxFiles > 0 {
+		for i := l.maxFiles - 1; i >= 1; i-- {
+			err := os.Rename(l.path+"."+strconv.Itoa(i), l.path+"."+strconv.Itoa(i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(l.path); err != nil {
+		return err
+	}
+
+	return l.open()
+}
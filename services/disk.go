@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/mineleaguedev/luximo/models"
+)
+
+func (s *BlobStore) EnsureSpace(required int64) error {
+	if required <= 0 {
+		return nil
+	}
+
+	available, ok := freeSpace(s.paths.BlobsPath)
+	if !ok {
+		return nil
+	}
+
+	if available-s.reserve < required {
+		return NewError(models.ErrorDiskFull, fmt.Sprintf(
+			"not enough disk space: %s required, %s available with %s reserved",
+			formatBytes(required), formatBytes(available), formatBytes(s.reserve),
+		), nil)
+	}
+
+	return nil
+}
+
+func artifactSize(ctx context.Context, client *Client, known int64, path string) (int64, error) {
+	if known > 0 {
+		return known, nil
+	}
+
+	return client.Size(ctx, path)
+}
+
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f%cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package services
+
+func freeSpace(path string) (int64, bool) {
+	return 0, false
+}
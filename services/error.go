@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/mineleaguedev/luximo/models"
+	"net/http"
+	"syscall"
+)
+
+type Error struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func NewError(code, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	if e.Message == "" {
+		return e.Err.Error()
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func upstreamStatusError(message string, resp *http.Response) error {
+	if resp.StatusCode >= 500 {
+		return NewError(models.ErrorUpstreamUnreachable, message+": "+resp.Status, nil)
+	}
+
+	return NewError(models.ErrorUpstreamRejected, message+": "+resp.Status, nil)
+}
+
+func ErrorCode(err error) string {
+	if errors.Is(err, syscall.ENOSPC) {
+		return models.ErrorDiskFull
+	}
+
+	var serviceErr *Error
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Code
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return models.ErrorCanceled
+	}
+
+	return models.ErrorInternal
+}
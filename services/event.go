@@ -0,0 +1,72 @@
+package services
+
+import (
+	"github.com/mineleaguedev/luximo/models"
+	"sync"
+	"time"
+)
+
+type EventService struct {
+	mutex       sync.RWMutex
+	subscribers map[chan models.Event]string
+	closed      bool
+}
+
+func NewEventService() *EventService {
+	return &EventService{subscribers: make(map[chan models.Event]string)}
+}
+
+func (s *EventService) Subscribe(jobID string) (<-chan models.Event, func()) {
+	events := make(chan models.Event, 256)
+
+	s.mutex.Lock()
+	if s.closed {
+		close(events)
+	} else {
+		s.subscribers[events] = jobID
+	}
+	s.mutex.Unlock()
+
+	var once sync.Once
+	return events, func() {
+		once.Do(func() {
+			s.mutex.Lock()
+			if _, ok := s.subscribers[events]; ok {
+				delete(s.subscribers, events)
+				close(events)
+			}
+			s.mutex.Unlock()
+		})
+	}
+}
+
+func (s *EventService) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+	for events := range s.subscribers {
+		delete(s.subscribers, events)
+		close(events)
+	}
+}
+
+func (s *EventService) Publish(event models.Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for events, jobID := range s.subscribers {
+		if jobID != "" && jobID != event.JobID {
+			continue
+		}
+
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"github.com/hashicorp/go-version"
+	"github.com/mineleaguedev/luximo/models"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type GCService struct {
+	paths     models.Paths
+	retention map[string]models.Retention
+	blobs     *BlobStore
+}
+
+type gcVersion struct {
+	artifact  string
+	version   string
+	keys      []string
+	size      int64
+	usedAt    time.Time
+	installed bool
+}
+
+func NewGCService(paths models.Paths, config models.Config, blobs *BlobStore) *GCService {
+	return &GCService{paths: paths, retention: config.Retention, blobs: blobs}
+}
+
+func (s *GCService) CollectGarbage(ctx context.Context, tracker Tracker) error {
+	entries := s.blobs.entries()
+	referenced := s.referenced()
+
+	kinds := make(map[string]map[string]map[string]*gcVersion)
+	for key, entry := range entries {
+		artifact, artifactVersion, ok := splitBlobKey(key)
+		if !ok {
+			continue
+		}
+		kind := strings.Split(artifact, "/")[0]
+
+		if kinds[kind] == nil {
+			kinds[kind] = make(map[string]map[string]*gcVersion)
+		}
+		if kinds[kind][artifact] == nil {
+			kinds[kind][artifact] = make(map[string]*gcVersion)
+		}
+
+		versionInfo, ok := kinds[kind][artifact][artifactVersion]
+		if !ok {
+			versionInfo = &gcVersion{
+				artifact:  artifact,
+				version:   artifactVersion,
+				installed: referenced[artifact+"/"+artifactVersion] || s.installed(artifact, artifactVersion),
+			}
+			kinds[kind][artifact][artifactVersion] = versionInfo
+		}
+		versionInfo.keys = append(versionInfo.keys, key)
+		versionInfo.size += s.blobs.blobSize(entry.Sum)
+		if entry.UsedAt.After(versionInfo.usedAt) {
+			versionInfo.usedAt = entry.UsedAt
+		}
+	}
+
+	var expired []*gcVersion
+	for kind, artifacts := range kinds {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		expired = append(expired, s.expiredVersions(s.retention[kind], artifacts)...)
+	}
+
+	var keys []string
+	for _, versionInfo := range expired {
+		keys = append(keys, versionInfo.keys...)
+	}
+	if err := s.blobs.Forget(keys); err != nil {
+		return err
+	}
+
+	for _, versionInfo := range expired {
+		tracker.Removed(versionInfo.artifact + "/" + versionInfo.version)
+	}
+
+	_, err := s.blobs.Prune()
+	return err
+}
+
+func (s *GCService) expiredVersions(retention models.Retention, artifacts map[string]map[string]*gcVersion) []*gcVersion {
+	var expired, candidates []*gcVersion
+	var total int64
+
+	for _, versions := range artifacts {
+		sorted := make([]*gcVersion, 0, len(versions))
+		for _, versionInfo := range versions {
+			sorted = append(sorted, versionInfo)
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			return newerVersion(sorted[i].version, sorted[j].version)
+		})
+
+		for index, versionInfo := range sorted {
+			if versionInfo.installed {
+				total += versionInfo.size
+				continue
+			}
+
+			if retention.KeepVersions > 0 && index >= retention.KeepVersions {
+				expired = append(expired, versionInfo)
+				continue
+			}
+
+			if retention.KeepDays > 0 && time.Since(versionInfo.usedAt) > time.Duration(retention.KeepDays)*24*time.Hour {
+				expired = append(expired, versionInfo)
+				continue
+			}
+
+			total += versionInfo.size
+			candidates = append(candidates, versionInfo)
+		}
+	}
+
+	if retention.MaxBytes <= 0 {
+		return expired
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].usedAt.Before(candidates[j].usedAt)
+	})
+	for _, versionInfo := range candidates {
+		if total <= retention.MaxBytes {
+			break
+		}
+
+		total -= versionInfo.size
+		expired = append(expired, versionInfo)
+	}
+
+	return expired
+}
+
+func (s *GCService) installed(artifact, artifactVersion string) bool {
+	var installedPath string
+
+	parts := strings.Split(artifact, "/")
+	switch parts[0] {
+	case "plugin":
+		installedPath = s.paths.PluginsPath + parts[1] + "-" + artifactVersion + ".jar"
+	case "map":
+		installedPath = s.paths.MapsPath + parts[1] + "/" + parts[2] + "/" + parts[3] + "-" + artifactVersion
+	case "paper":
+		installedPath = s.paths.PaperPath + "paper-" + artifactVersion + ".rar"
+	case "velocity":
+		installedPath = s.paths.VelocityPath + "velocity-" + artifactVersion + ".rar"
+	default:
+		return true
+	}
+
+	_, err := os.Stat(installedPath)
+	return err == nil
+}
+
+func (s *GCService) referenced() map[string]bool {
+	referenced := make(map[string]bool)
+
+	for _, root := range []string{s.paths.LobbyServersPath, s.paths.MiniServersPath, s.paths.MegaServersPath, s.paths.ProxyServersPath} {
+		servers, err := loadServers(root)
+		if err != nil {
+			continue
+		}
+
+		for _, server := range servers {
+			for _, plugin := range server.Plugins {
+				if index := strings.LastIndex(plugin, "-"); index > 0 {
+					referenced["plugin/"+plugin[:index]+"/"+plugin[index+1:]] = true
+				}
+			}
+			if server.Map != "" {
+				if index := strings.LastIndex(server.Map, "-"); index > 0 {
+					referenced["map/"+server.Map[:index]+"/"+server.Map[index+1:]] = true
+				}
+			}
+			if server.Paper != "" {
+				referenced["paper/"+server.Paper] = true
+			}
+			if server.Velocity != "" {
+				referenced["velocity/"+server.Velocity] = true
+			}
+		}
+	}
+
+	return referenced
+}
+
+func splitBlobKey(key string) (string, string, bool) {
+	parts := strings.Split(key, "/")
+
+	switch parts[0] {
+	case "plugin":
+		if len(parts) != 3 {
+			return "", "", false
+		}
+		return parts[0] + "/" + parts[1], parts[2], true
+	case "map":
+		if len(parts) != 6 {
+			return "", "", false
+		}
+		return strings.Join(parts[:4], "/"), parts[4], true
+	case "paper", "velocity":
+		if len(parts) != 2 {
+			return "", "", false
+		}
+		return parts[0], parts[1], true
+	}
+
+	return "", "", false
+}
+
+func newerVersion(a, b string) bool {
+	aVersion, aErr := version.NewVersion(a)
+	bVersion, bErr := version.NewVersion(b)
+	if aErr != nil || bErr != nil {
+		return aErr == nil
+	}
+
+	return aVersion.GreaterThan(bVersion)
+}
@@ -0,0 +1,450 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type JobService struct {
+	paths    models.Paths
+	history  int
+	events   *EventService
+	status   *StatusService
+	ctx      context.Context
+	cancel   context.CancelFunc
+	running  sync.WaitGroup
+	mutex    sync.RWMutex
+	jobs     map[string]*models.Job
+	order    []string
+	stopping bool
+}
+
+func NewJobService(paths models.Paths, config models.Config, events *EventService, status *StatusService) *JobService {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &JobService{
+		paths:   paths,
+		history: config.JobsHistory,
+		events:  events,
+		status:  status,
+		ctx:     ctx,
+		cancel:  cancel,
+		jobs:    make(map[string]*models.Job),
+	}
+
+	if err := s.loadJobs(); err != nil {
+		log.Printf("Error loading jobs history: %s", err.Error())
+	}
+
+	return s
+}
+
+func (s *JobService) StartJob(kind string, run func(ctx context.Context, tracker Tracker) error) (models.Job, error) {
+	job, err := s.createJob(kind)
+	if err != nil {
+		return job, err
+	}
+
+	go func() {
+		defer s.running.Done()
+		s.runJob(s.ctx, job.ID, job.Kind, run)
+	}()
+
+	return job, nil
+}
+
+func (s *JobService) RunJob(kind string, run func(ctx context.Context, tracker Tracker) error) (models.Job, error) {
+	job, err := s.createJob(kind)
+	if err != nil {
+		return job, err
+	}
+	defer s.running.Done()
+
+	err = s.runJob(s.ctx, job.ID, job.Kind, run)
+
+	job, _ = s.GetJob(job.ID)
+	return job, err
+}
+
+func (s *JobService) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.stopping = true
+	s.mutex.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+	}
+
+	log.Printf("Grace period expired, aborting running jobs")
+	s.cancel()
+	<-finished
+
+	return ctx.Err()
+}
+
+func (s *JobService) GetJob(id string) (models.Job, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return models.Job{}, NewError(models.ErrorNotFound, "job "+id+" not found", nil)
+	}
+
+	return copyJob(job), nil
+}
+
+func (s *JobService) GetJobs() []models.Job {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	jobs := make([]models.Job, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, copyJob(s.jobs[s.order[i]]))
+	}
+
+	return jobs
+}
+
+func (s *JobService) createJob(kind string) (models.Job, error) {
+	job := &models.Job{
+		ID:        newJobID(),
+		Kind:      kind,
+		State:     models.JobQueued,
+		CreatedAt: time.Now(),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopping {
+		return models.Job{}, NewError(models.ErrorShuttingDown, "luximo is shutting down", nil)
+	}
+
+	for _, id := range s.order {
+		if running := s.jobs[id]; running.Kind == kind && (running.State == models.JobQueued || running.State == models.JobRunning) {
+			return models.Job{}, NewError(models.ErrorConflict, kind+" sync is already running as job "+running.ID, nil)
+		}
+	}
+
+	s.running.Add(1)
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	s.pruneJobs()
+	s.saveJob(job)
+
+	return copyJob(job), nil
+}
+
+func (s *JobService) runJob(ctx context.Context, id, kind string, run func(ctx context.Context, tracker Tracker) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	s.update(id, true, func(job *models.Job) {
+		job.State = models.JobRunning
+	})
+	s.events.Publish(models.Event{
+		Type:  models.EventSyncStarted,
+		JobID: id,
+		Kind:  kind,
+	})
+
+	err := run(ctx, &jobTracker{service: s, id: id, kind: kind, downloads: make(map[string]*trackedDownload)})
+
+	event := models.Event{
+		Type:  models.EventSyncCompleted,
+		JobID: id,
+		Kind:  kind,
+		State: models.JobSucceeded,
+	}
+	s.update(id, true, func(job *models.Job) {
+		now := time.Now()
+		job.FinishedAt = &now
+		if err != nil && ctx.Err() != nil {
+			job.State = models.JobCanceled
+			job.Error = err.Error()
+			job.ErrorCode = models.ErrorCanceled
+		} else if err != nil {
+			job.State = models.JobFailed
+			job.Error = err.Error()
+			job.ErrorCode = ErrorCode(err)
+		} else {
+			job.State = models.JobSucceeded
+		}
+		event.State = job.State
+		event.Error = job.Error
+		event.Code = job.ErrorCode
+		event.Items = job.Progress.ItemsDone
+		event.Bytes = job.Progress.BytesDone
+	})
+	s.events.Publish(event)
+
+	if job, err := s.GetJob(id); err == nil {
+		s.status.JobFinished(job)
+	}
+
+	return err
+}
+
+func (s *JobService) update(id string, persist bool, fn func(job *models.Job)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return
+	}
+
+	fn(job)
+
+	if persist {
+		s.saveJob(job)
+	}
+}
+
+func (s *JobService) pruneJobs() {
+	for len(s.order) > s.history {
+		id := s.order[0]
+		if state := s.jobs[id].State; state == models.JobQueued || state == models.JobRunning {
+			break
+		}
+
+		s.order = s.order[1:]
+		delete(s.jobs, id)
+		if err := os.RemoveAll(s.paths.JobsPath + id + ".json"); err != nil {
+			log.Printf("Error removing job %s: %s", id, err.Error())
+		}
+	}
+}
+
+func (s *JobService) saveJob(job *models.Job) {
+	jobBytes, err := json.Marshal(job)
+	if err != nil {
+		log.Printf("Error saving job %s: %s", job.ID, err.Error())
+		return
+	}
+
+	if err := os.WriteFile(s.paths.JobsPath+job.ID+".json", jobBytes, 0644); err != nil {
+		log.Printf("Error saving job %s: %s", job.ID, err.Error())
+	}
+}
+
+func (s *JobService) loadJobs() error {
+	jobFiles, err := os.ReadDir(s.paths.JobsPath)
+	if err != nil {
+		return err
+	}
+
+	var jobs []*models.Job
+	for _, jobFile := range jobFiles {
+		if !strings.HasSuffix(jobFile.Name(), ".json") {
+			continue
+		}
+
+		jobBytes, err := os.ReadFile(s.paths.JobsPath + jobFile.Name())
+		if err != nil {
+			return err
+		}
+
+		var job models.Job
+		if err := json.Unmarshal(jobBytes, &job); err != nil {
+			log.Printf("Error loading job %s: %s", jobFile.Name(), err.Error())
+			continue
+		}
+
+		jobs = append(jobs, &job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, job := range jobs {
+		if job.State == models.JobQueued || job.State == models.JobRunning {
+			now := time.Now()
+			job.State = models.JobFailed
+			job.Error = "interrupted by restart"
+			job.ErrorCode = models.ErrorCanceled
+			job.FinishedAt = &now
+			s.saveJob(job)
+		}
+
+		s.jobs[job.ID] = job
+		s.order = append(s.order, job.ID)
+	}
+	s.pruneJobs()
+
+	return nil
+}
+
+func copyJob(job *models.Job) models.Job {
+	jobCopy := *job
+	jobCopy.Report.Installed = append([]string(nil), job.Report.Installed...)
+	jobCopy.Report.Removed = append([]string(nil), job.Report.Removed...)
+	return jobCopy
+}
+
+func newJobID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return strings.ReplaceAll(time.Now().Format("20060102150405.000000000"), ".", "")
+	}
+	return hex.EncodeToString(id)
+}
+
+type jobTracker struct {
+	service   *JobService
+	id        string
+	kind      string
+	mutex     sync.Mutex
+	downloads map[string]*trackedDownload
+}
+
+type trackedDownload struct {
+	done      int64
+	total     int64
+	published time.Time
+}
+
+func (t *jobTracker) ListingFetched(items int, notModified bool) {
+	t.publish(models.Event{
+		Type:   models.EventListingFetched,
+		Items:  items,
+		Cached: notModified,
+	})
+}
+
+func (t *jobTracker) Stale(since time.Time) {
+	t.service.update(t.id, false, func(job *models.Job) {
+		job.Report.StaleSince = &since
+	})
+
+	t.publish(models.Event{
+		Type:  models.EventListingStale,
+		Since: &since,
+	})
+}
+
+func (t *jobTracker) Planned(items int) {
+	t.service.update(t.id, false, func(job *models.Job) {
+		job.Progress.ItemsTotal += items
+	})
+}
+
+func (t *jobTracker) DownloadStarted(name string, size int64) {
+	t.mutex.Lock()
+	t.downloads[name] = &trackedDownload{total: size, published: time.Now()}
+	t.mutex.Unlock()
+
+	if size > 0 {
+		t.service.update(t.id, false, func(job *models.Job) {
+			job.Progress.BytesTotal += size
+		})
+	}
+
+	t.publish(models.Event{
+		Type:  models.EventDownloadStarted,
+		Name:  name,
+		Total: size,
+	})
+}
+
+func (t *jobTracker) DownloadProgress(name string, n int64) {
+	t.service.update(t.id, false, func(job *models.Job) {
+		job.Progress.BytesDone += n
+	})
+
+	t.mutex.Lock()
+	download, ok := t.downloads[name]
+	if !ok {
+		t.mutex.Unlock()
+		return
+	}
+	download.done += n
+	if time.Since(download.published) < 250*time.Millisecond {
+		t.mutex.Unlock()
+		return
+	}
+	download.published = time.Now()
+	event := models.Event{
+		Type:  models.EventDownloadProgress,
+		Name:  name,
+		Bytes: download.done,
+		Total: download.total,
+	}
+	t.mutex.Unlock()
+
+	t.publish(event)
+}
+
+func (t *jobTracker) DownloadFinished(name string) {
+	t.mutex.Lock()
+	var done int64
+	if download, ok := t.downloads[name]; ok {
+		done = download.done
+		delete(t.downloads, name)
+	}
+	t.mutex.Unlock()
+
+	t.publish(models.Event{
+		Type:  models.EventDownloadFinished,
+		Name:  name,
+		Bytes: done,
+	})
+}
+
+func (t *jobTracker) Installed(name string) {
+	t.service.update(t.id, false, func(job *models.Job) {
+		job.Progress.ItemsDone++
+		job.Report.Installed = append(job.Report.Installed, name)
+	})
+
+	t.publish(models.Event{
+		Type: models.EventInstalled,
+		Name: name,
+	})
+}
+
+func (t *jobTracker) Removed(name string) {
+	t.service.update(t.id, false, func(job *models.Job) {
+		job.Report.Removed = append(job.Report.Removed, name)
+	})
+
+	t.publish(models.Event{
+		Type: models.EventRemoved,
+		Name: name,
+	})
+}
+
+func (t *jobTracker) publish(event models.Event) {
+	event.JobID = t.id
+	event.Kind = t.kind
+	t.service.events.Publish(event)
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+type ListingCache struct {
+	paths   models.Paths
+	client  *Client
+	offline bool
+	mutex   sync.Mutex
+}
+
+type Listing struct {
+	Body        []byte
+	NotModified bool
+	Stale       bool
+	FetchedAt   time.Time
+}
+
+type listingMeta struct {
+	Path         string    `json:"path"`
+	ETag         string    `json:"etag"`
+	LastModified string    `json:"lastModified"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+	Synced       bool      `json:"synced"`
+}
+
+func NewListingCache(paths models.Paths, config models.Config, client *Client) *ListingCache {
+	return &ListingCache{paths: paths, client: client, offline: config.OfflineFallback}
+}
+
+func (c *ListingCache) Fetch(ctx context.Context, name, path string) (*Listing, error) {
+	listing, err := c.fetch(ctx, name, path)
+	if err == nil || !c.offline || ctx.Err() != nil || ErrorCode(err) != models.ErrorUpstreamUnreachable {
+		return listing, err
+	}
+
+	meta, metaErr := c.readMeta(name)
+	body, bodyErr := os.ReadFile(c.paths.CachePath + name + ".json")
+	if metaErr != nil || bodyErr != nil {
+		return nil, err
+	}
+
+	log.Printf("Upstream unavailable, using %s list cached at %s: %s", name, meta.FetchedAt.Format(time.RFC3339), err.Error())
+	return &Listing{Body: body, Stale: true, FetchedAt: meta.FetchedAt}, nil
+}
+
+func (c *ListingCache) fetch(ctx context.Context, name, path string) (*Listing, error) {
+	meta, _ := c.readMeta(name)
+
+	header := make(http.Header)
+	if meta != nil && meta.Synced && meta.Path == path {
+		if meta.ETag != "" {
+			header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+
+	resp, err := c.client.Do(ctx, path, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && len(header) > 0 {
+		body, err := os.ReadFile(c.paths.CachePath + name + ".json")
+		if err == nil {
+			now := time.Now()
+			meta.FetchedAt = now
+			if err := c.save(name, body, meta); err != nil {
+				return nil, err
+			}
+
+			return &Listing{Body: body, NotModified: true, FetchedAt: now}, nil
+		}
+
+		if err := c.Invalidate(name); err != nil {
+			return nil, err
+		}
+		return c.fetch(ctx, name, path)
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, upstreamStatusError("error getting "+name+" list from API", resp)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	meta = &listingMeta{
+		Path:         path,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+	}
+	if err := c.save(name, body, meta); err != nil {
+		return nil, err
+	}
+
+	return &Listing{Body: body, FetchedAt: meta.FetchedAt}, nil
+}
+
+func (c *ListingCache) MarkSynced(name string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	meta, err := c.readMeta(name)
+	if err != nil {
+		return err
+	}
+	meta.Synced = true
+
+	return c.writeMeta(name, meta)
+}
+
+func (c *ListingCache) Invalidate(name string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := os.RemoveAll(c.paths.CachePath + name + ".meta"); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(c.paths.CachePath + name + ".json")
+}
+
+func (c *ListingCache) save(name string, body []byte, meta *listingMeta) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := writeStaged(c.paths, c.paths.CachePath+name+".json", body); err != nil {
+		return err
+	}
+
+	return c.writeMeta(name, meta)
+}
+
+func (c *ListingCache) readMeta(name string) (*listingMeta, error) {
+	metaBytes, err := os.ReadFile(c.paths.CachePath + name + ".meta")
+	if err != nil {
+		return nil, err
+	}
+
+	var meta listingMeta
+	if err := json.Unmarshal(metaBytes, &meta); err != nil {
+		return nil, err
+	}
+
+	return &meta, nil
+}
+
+func (c *ListingCache) writeMeta(name string, meta *listingMeta) error {
+	metaBytes, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	return writeStaged(c.paths, c.paths.CachePath+name+".meta", metaBytes)
+}
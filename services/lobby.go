@@ -0,0 +1,53 @@
+package services
+
+import (
+	"github.com/mineleaguedev/luximo/models"
+	"sync"
+)
+
+type LobbyServerService struct {
+	paths      models.Paths
+	config     models.ServerConfig
+	supervisor *SupervisorService
+	ports      *PortAllocator
+	changed    func()
+	mutex      sync.Mutex
+}
+
+func NewLobbyServerService(paths models.Paths, config models.Config, supervisor *SupervisorService, ports *PortAllocator, changed func()) *LobbyServerService {
+	return &LobbyServerService{paths: paths, config: config.Lobby, supervisor: supervisor, ports: ports, changed: changed}
+}
+
+func (s *LobbyServerService) CreateLobbyServer(request models.CreateServerRequest) (*models.Server, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	server, err := newServer(s.paths, models.ServerLobby, models.ServerLobby, request, s.ports)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := provisionServer(s.paths, s.paths.LobbyServersPath, &server, s.config.Plugins, s.config.World, s.config.Properties, nil); err != nil {
+		s.ports.Release(models.ServerLobby, server.Name)
+		return nil, err
+	}
+	s.changed()
+
+	return &server, nil
+}
+
+func (s *LobbyServerService) GetLobbyServers() ([]models.Server, error) {
+	return loadServers(s.paths.LobbyServersPath)
+}
+
+func (s *LobbyServerService) DeleteLobbyServer(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := deleteServer(s.paths.LobbyServersPath, models.ServerLobby, name, s.supervisor, s.ports); err != nil {
+		return err
+	}
+	s.changed()
+
+	return nil
+}
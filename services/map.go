@@ -1,44 +1,73 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"github.com/hashicorp/go-version"
 	"github.com/mineleaguedev/luximo/models"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"sort"
 	"strings"
 )
 
 type MapService struct {
-	paths models.Paths
+	paths    models.Paths
+	client   *Client
+	listings *ListingCache
+	blobs    *BlobStore
 }
 
-func NewMapService(paths models.Paths) *MapService {
-	return &MapService{paths: paths}
+func NewMapService(paths models.Paths, client *Client, listings *ListingCache, blobs *BlobStore) *MapService {
+	return &MapService{paths: paths, client: client, listings: listings, blobs: blobs}
 }
 
-func (s *MapService) UpdateMaps() error {
+func (s *MapService) UpdateMaps(ctx context.Context, tracker Tracker) error {
+	mapsInfo, listing, err := s.getMapsInfo(ctx)
+	if err != nil {
+		return err
+	}
+	tracker.ListingFetched(countMaps(mapsInfo), listing.NotModified)
+
+	if listing.Stale {
+		tracker.Stale(listing.FetchedAt)
+		return nil
+	}
+
+	if listing.NotModified {
+		return nil
+	}
+
+	if err := s.syncMaps(ctx, tracker, mapsInfo); err != nil {
+		return err
+	}
+
+	return s.listings.MarkSynced("map")
+}
+
+func (s *MapService) syncMaps(ctx context.Context, tracker Tracker, mapsInfo []models.MiniGames) error {
 	var minigamesArr []models.MiniGames
 
-	mapsInfo, err := s.GetMapsInfo()
+	required, err := s.requiredMapsBytes(ctx, mapsInfo)
 	if err != nil {
 		return err
 	}
 
+	if err := s.blobs.EnsureSpace(required); err != nil {
+		return err
+	}
+
 	minigames, err := os.ReadDir(s.paths.MapsPath)
 	if err != nil {
 		return err
 	}
 
 	if len(minigames) == 0 {
-		if err := s.deleteOldAndWrongMaps(minigamesArr, mapsInfo); err != nil {
+		if err := s.deleteOldAndWrongMaps(tracker, minigamesArr, mapsInfo); err != nil {
 			return err
 		}
 
-		if err := s.addNewMaps(mapsInfo); err != nil {
+		if err := s.addNewMaps(ctx, tracker, mapsInfo); err != nil {
 			return err
 		}
 
@@ -80,6 +109,7 @@ func (s *MapService) UpdateMaps() error {
 						if err := os.RemoveAll(s.paths.MapsPath + minigame.Name() + "/" + format.Name() + "/" + mapVersion.Name() + "/" + mapFile.Name()); err != nil {
 							return err
 						}
+						tracker.Removed(minigame.Name() + "/" + format.Name() + "/" + mapVersion.Name() + "/" + mapFile.Name())
 					}
 				}
 
@@ -103,18 +133,18 @@ func (s *MapService) UpdateMaps() error {
 		})
 	}
 
-	if err := s.deleteOldAndWrongMaps(minigamesArr, mapsInfo); err != nil {
+	if err := s.deleteOldAndWrongMaps(tracker, minigamesArr, mapsInfo); err != nil {
 		return err
 	}
 
-	if err := s.addNewMaps(mapsInfo); err != nil {
+	if err := s.addNewMaps(ctx, tracker, mapsInfo); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (s *MapService) deleteOldAndWrongMaps(minigamesArr, mapsInfo []models.MiniGames) error {
+func (s *MapService) deleteOldAndWrongMaps(tracker Tracker, minigamesArr, mapsInfo []models.MiniGames) error {
 	for _, minigame := range minigamesArr {
 		var hasMinigame bool
 		for _, minigameInfo := range mapsInfo {
@@ -143,6 +173,7 @@ func (s *MapService) deleteOldAndWrongMaps(minigamesArr, mapsInfo []models.MiniG
 								if err := os.RemoveAll(s.paths.MapsPath + minigame.Name + "/" + format.Format + "/" + formatMap.Name + "-" + formatMap.LastVersion); err != nil {
 									return err
 								}
+								tracker.Removed(minigame.Name + "/" + format.Format + "/" + formatMap.Name + "-" + formatMap.LastVersion)
 							}
 						}
 
@@ -150,6 +181,7 @@ func (s *MapService) deleteOldAndWrongMaps(minigamesArr, mapsInfo []models.MiniG
 							if err := os.RemoveAll(s.paths.MapsPath + minigame.Name + "/" + format.Format + "/" + formatMap.Name + "-" + formatMap.LastVersion); err != nil {
 								return err
 							}
+							tracker.Removed(minigame.Name + "/" + format.Format + "/" + formatMap.Name + "-" + formatMap.LastVersion)
 						}
 					}
 				}
@@ -158,6 +190,7 @@ func (s *MapService) deleteOldAndWrongMaps(minigamesArr, mapsInfo []models.MiniG
 					if err := os.RemoveAll(s.paths.MapsPath + minigame.Name + "/" + format.Format); err != nil {
 						return err
 					}
+					tracker.Removed(minigame.Name + "/" + format.Format)
 				}
 			}
 		}
@@ -166,31 +199,36 @@ func (s *MapService) deleteOldAndWrongMaps(minigamesArr, mapsInfo []models.MiniG
 			if err := os.RemoveAll(s.paths.MapsPath + minigame.Name); err != nil {
 				return err
 			}
+			tracker.Removed(minigame.Name)
 		}
 	}
 
 	return nil
 }
 
-func (s *MapService) addNewMaps(mapsInfo []models.MiniGames) error {
+func (s *MapService) addNewMaps(ctx context.Context, tracker Tracker, mapsInfo []models.MiniGames) error {
+	var newMaps int
 	for _, minigameInfo := range mapsInfo {
 		for _, formatInfo := range minigameInfo.Formats {
 			for _, mapInfo := range formatInfo.Maps {
 				_, err := os.Stat(s.paths.MapsPath + minigameInfo.Name + "/" + formatInfo.Format + "/" + mapInfo.Name + "-" + mapInfo.LastVersion)
 				if os.IsNotExist(err) {
-					mapWorldFileBytes, err := s.DownloadMapWorld(minigameInfo.Name, formatInfo.Format, mapInfo.Name, mapInfo.LastVersion)
-					if err != nil {
-						return err
-					}
-
-					mapConfigFileBytes, err := s.DownloadMapConfig(minigameInfo.Name, formatInfo.Format, mapInfo.Name, mapInfo.LastVersion)
-					if err != nil {
-						return err
-					}
+					newMaps++
+				}
+			}
+		}
+	}
+	tracker.Planned(newMaps)
 
-					if err := s.UpdateMap(minigameInfo.Name, formatInfo.Format, mapInfo.Name, mapInfo.LastVersion, mapWorldFileBytes, mapConfigFileBytes); err != nil {
+	for _, minigameInfo := range mapsInfo {
+		for _, formatInfo := range minigameInfo.Formats {
+			for _, mapInfo := range formatInfo.Maps {
+				_, err := os.Stat(s.paths.MapsPath + minigameInfo.Name + "/" + formatInfo.Format + "/" + mapInfo.Name + "-" + mapInfo.LastVersion)
+				if os.IsNotExist(err) {
+					if err := s.installMap(ctx, tracker, minigameInfo.Name, formatInfo.Format, mapInfo.Name, mapInfo.LastVersion, mapInfo.Checksums[mapInfo.LastVersion]); err != nil {
 						return err
 					}
+					tracker.Installed(minigameInfo.Name + "/" + formatInfo.Format + "/" + mapInfo.Name + "-" + mapInfo.LastVersion)
 				}
 			}
 		}
@@ -199,33 +237,159 @@ func (s *MapService) addNewMaps(mapsInfo []models.MiniGames) error {
 	return nil
 }
 
-func (s *MapService) GetMapsInfo() ([]models.MiniGames, error) {
-	resp, err := http.Get("https://api.mineleague.ru/map")
-	if err != nil {
-		return nil, err
+func (s *MapService) installMap(ctx context.Context, tracker Tracker, minigame, format, mapName, version string, checksums models.MapChecksums) error {
+	key := "map/" + minigame + "/" + format + "/" + mapName + "/" + version
+	folder := s.paths.MapsPath + minigame + "/" + format + "/" + mapName + "-" + version + "/"
+
+	worldSum, hasWorld := s.blobs.Lookup(key+"/world", checksums.World)
+	configSum, hasConfig := s.blobs.Lookup(key+"/config", checksums.Config)
+
+	var mapWorldFileBytes, mapConfigFileBytes *[]byte
+	var err error
+	if !hasWorld {
+		mapWorldFileBytes, err = s.DownloadMapWorld(ctx, tracker, minigame, format, mapName, version)
+		if err != nil {
+			return err
+		}
+
+		if err := s.blobs.Verify(minigame+"/"+format+"/"+mapName+"-"+version+"/world.rar", checksums.World, *mapWorldFileBytes); err != nil {
+			return err
+		}
+	}
+
+	if !hasConfig {
+		mapConfigFileBytes, err = s.DownloadMapConfig(ctx, tracker, minigame, format, mapName, version)
+		if err != nil {
+			return err
+		}
+
+		if err := s.blobs.Verify(minigame+"/"+format+"/"+mapName+"-"+version+"/map.yml", checksums.Config, *mapConfigFileBytes); err != nil {
+			return err
+		}
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return err
+	}
+
+	if hasWorld {
+		if err := s.blobs.Link(worldSum, folder+"world.rar"); err != nil {
+			return err
+		}
+	}
+
+	if hasConfig {
+		if err := s.blobs.Link(configSum, folder+"map.yml"); err != nil {
+			return err
+		}
+	}
+
+	return s.UpdateMap(minigame, format, mapName, version, mapWorldFileBytes, mapConfigFileBytes)
+}
+
+func (s *MapService) requiredMapsBytes(ctx context.Context, mapsInfo []models.MiniGames) (int64, error) {
+	var required int64
+	for _, minigameInfo := range mapsInfo {
+		for _, formatInfo := range minigameInfo.Formats {
+			for _, mapInfo := range formatInfo.Maps {
+				folder := s.paths.MapsPath + minigameInfo.Name + "/" + formatInfo.Format + "/" + mapInfo.Name + "-" + mapInfo.LastVersion + "/"
+				_, worldErr := os.Stat(folder + "world.rar")
+				_, configErr := os.Stat(folder + "map.yml")
+				if worldErr == nil && configErr == nil {
+					continue
+				}
+
+				size, err := s.requiredMapBytes(ctx, minigameInfo.Name, formatInfo.Format, mapInfo)
+				if err != nil {
+					return 0, err
+				}
+				required += size
+			}
+		}
+	}
+
+	return required, nil
+}
+
+func (s *MapService) requiredMapBytes(ctx context.Context, minigame, format string, mapInfo models.Map) (int64, error) {
+	key := "map/" + minigame + "/" + format + "/" + mapInfo.Name + "/" + mapInfo.LastVersion
+	path := "/map/" + minigame + "/" + format + "/" + mapInfo.Name + "/" + mapInfo.LastVersion
+	checksums := mapInfo.Checksums[mapInfo.LastVersion]
+
+	hasWorld := s.blobs.Has(key+"/world", checksums.World)
+	hasConfig := s.blobs.Has(key+"/config", checksums.Config)
+	if hasWorld && hasConfig {
+		return 0, nil
+	}
+
+	if size := mapInfo.Sizes[mapInfo.LastVersion]; size > 0 {
+		return size, nil
+	}
+
+	var required int64
+	if !hasWorld {
+		size, err := s.client.Size(ctx, path+"/world")
+		if err != nil {
+			return 0, err
+		}
+		required += size
+	}
+
+	if !hasConfig {
+		size, err := s.client.Size(ctx, path+"/config")
+		if err != nil {
+			return 0, err
+		}
+		required += size
+	}
+
+	return required, nil
+}
+
+func countMaps(minigames []models.MiniGames) int {
+	var count int
+	for _, minigame := range minigames {
+		for _, format := range minigame.Formats {
+			count += len(format.Maps)
+		}
+	}
+	return count
+}
+
+func (s *MapService) GetMapsInfo(ctx context.Context) ([]models.MiniGames, error) {
+	mapsInfo, _, err := s.getMapsInfo(ctx)
+	return mapsInfo, err
+}
+
+func (s *MapService) getMapsInfo(ctx context.Context) ([]models.MiniGames, *Listing, error) {
+	listing, err := s.listings.Fetch(ctx, "map", "/map")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var response models.MapsResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, err
+	if err := json.Unmarshal(listing.Body, &response); err != nil {
+		return nil, nil, NewError(models.ErrorUpstreamRejected, "invalid response from API", err)
 	}
 
 	if !response.Success {
-		return nil, errors.New("error getting maps list from API")
+		return nil, nil, NewError(models.ErrorUpstreamRejected, "error getting maps list from API", nil)
 	}
 
 	for minigameIndex, minigame := range response.MiniGames {
 		for formatIndex, format := range minigame.Formats {
 			for mapIndex, minigameMap := range format.Maps {
+				name := minigame.Name + "/" + format.Format + "/" + minigameMap.Name
+				if len(minigameMap.Versions) == 0 {
+					return nil, nil, NewError(models.ErrorUpstreamRejected, "no versions of map "+name+" available from API", nil)
+				}
+
 				versions := make([]*version.Version, len(minigameMap.Versions))
 				for i, raw := range minigameMap.Versions {
-					v, _ := version.NewVersion(raw)
+					v, err := version.NewVersion(raw)
+					if err != nil {
+						return nil, nil, NewError(models.ErrorUpstreamRejected, "invalid version "+raw+" of map "+name+" from API", err)
+					}
 					versions[i] = v
 				}
 				sort.Sort(version.Collection(versions))
@@ -235,43 +399,49 @@ func (s *MapService) GetMapsInfo() ([]models.MiniGames, error) {
 		}
 	}
 
-	return response.MiniGames, nil
+	return response.MiniGames, listing, nil
 }
 
-func (s *MapService) DownloadMapWorld(minigame, format, minigameMap, version string) (*[]byte, error) {
-	resp, err := http.Get("https://api.mineleague.ru/map/" + minigame + "/" + format + "/" + minigameMap + "/" + version + "/world")
+func (s *MapService) DownloadMapWorld(ctx context.Context, tracker Tracker, minigame, format, minigameMap, version string) (*[]byte, error) {
+	resp, err := s.client.Get(ctx, "/map/"+minigame+"/"+format+"/"+minigameMap+"/"+version+"/world")
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, errors.New("error downloading map world from API")
+		return nil, upstreamStatusError("error downloading map world from API", resp)
 	}
 
-	fileBytes, err := ioutil.ReadAll(resp.Body)
+	name := minigame + "/" + format + "/" + minigameMap + "-" + version + "/world.rar"
+	tracker.DownloadStarted(name, resp.ContentLength)
+	fileBytes, err := ioutil.ReadAll(&progressReader{reader: resp.Body, name: name, tracker: tracker})
 	if err != nil {
 		return nil, err
 	}
+	tracker.DownloadFinished(name)
 
 	return &fileBytes, nil
 }
 
-func (s *MapService) DownloadMapConfig(minigame, format, minigameMap, version string) (*[]byte, error) {
-	resp, err := http.Get("https://api.mineleague.ru/map/" + minigame + "/" + format + "/" + minigameMap + "/" + version + "/config")
+func (s *MapService) DownloadMapConfig(ctx context.Context, tracker Tracker, minigame, format, minigameMap, version string) (*[]byte, error) {
+	resp, err := s.client.Get(ctx, "/map/"+minigame+"/"+format+"/"+minigameMap+"/"+version+"/config")
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, errors.New("error downloading map config from API")
+		return nil, upstreamStatusError("error downloading map config from API", resp)
 	}
 
-	fileBytes, err := ioutil.ReadAll(resp.Body)
+	name := minigame + "/" + format + "/" + minigameMap + "-" + version + "/map.yml"
+	tracker.DownloadStarted(name, resp.ContentLength)
+	fileBytes, err := ioutil.ReadAll(&progressReader{reader: resp.Body, name: name, tracker: tracker})
 	if err != nil {
 		return nil, err
 	}
+	tracker.DownloadFinished(name)
 
 	return &fileBytes, nil
 }
@@ -282,13 +452,7 @@ func (s *MapService) UpdateMap(minigame, format, mapName, version string, mapWor
 			return err
 		}
 
-		worldFile, err := os.Create(s.paths.MapsPath + minigame + "/" + format + "/" + mapName + "-" + version + "/" + "world.rar")
-		if err != nil {
-			return err
-		}
-		defer worldFile.Close()
-
-		if _, err = worldFile.Write(*mapWorldFileBytes); err != nil {
+		if err := s.blobs.Install("map/"+minigame+"/"+format+"/"+mapName+"/"+version+"/world", s.paths.MapsPath+minigame+"/"+format+"/"+mapName+"-"+version+"/world.rar", *mapWorldFileBytes); err != nil {
 			return err
 		}
 	}
@@ -298,13 +462,7 @@ func (s *MapService) UpdateMap(minigame, format, mapName, version string, mapWor
 			return err
 		}
 
-		configFile, err := os.Create(s.paths.MapsPath + minigame + "/" + format + "/" + mapName + "-" + version + "/" + "map.yml")
-		if err != nil {
-			return err
-		}
-		defer configFile.Close()
-
-		if _, err = configFile.Write(*mapConfigFileBytes); err != nil {
+		if err := s.blobs.Install("map/"+minigame+"/"+format+"/"+mapName+"/"+version+"/config", s.paths.MapsPath+minigame+"/"+format+"/"+mapName+"-"+version+"/map.yml", *mapConfigFileBytes); err != nil {
 			return err
 		}
 	}
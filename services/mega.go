@@ -0,0 +1,168 @@
+package services
+
+import (
+	"github.com/mineleaguedev/luximo/models"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type MegaServerService struct {
+	paths      models.Paths
+	config     models.ServerConfig
+	supervisor *SupervisorService
+	ports      *PortAllocator
+	changed    func()
+	mutex      sync.Mutex
+}
+
+func NewMegaServerService(paths models.Paths, config models.Config, supervisor *SupervisorService, ports *PortAllocator, changed func()) *MegaServerService {
+	return &MegaServerService{paths: paths, config: config.Mega, supervisor: supervisor, ports: ports, changed: changed}
+}
+
+func (s *MegaServerService) CreateMegaServer(request models.CreateServerRequest) (*models.Server, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	server, err := newServer(s.paths, models.ServerMega, models.ServerMega, request, s.ports)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := provisionServer(s.paths, s.paths.MegaServersPath, &server, s.config.Plugins, s.config.World, s.config.Properties, nil); err != nil {
+		s.ports.Release(models.ServerMega, server.Name)
+		return nil, err
+	}
+	s.changed()
+
+	return &server, nil
+}
+
+func (s *MegaServerService) GetMegaServers() ([]models.Server, error) {
+	return loadServers(s.paths.MegaServersPath)
+}
+
+func (s *MegaServerService) UpdateMegaServer(name string) (*models.Server, error) {
+	if err := validateNames(name); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	folder := s.paths.MegaServersPath + name + "/"
+	server, err := loadServer(folder)
+	if os.IsNotExist(err) {
+		return nil, NewError(models.ErrorNotFound, "mega server "+name+" not found", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.supervisor.Running(models.ServerMega, name) {
+		return nil, NewError(models.ErrorConflict, "mega server "+name+" is running", nil)
+	}
+
+	paperVersion, paperPath, err := currentBundle(s.paths.PaperPath, "paper")
+	if err != nil {
+		return nil, err
+	}
+
+	pluginPaths := make(map[string]string, len(s.config.Plugins))
+	plugins := []string{}
+	for _, pluginName := range s.config.Plugins {
+		pluginVersion, pluginPath, err := installedPlugin(s.paths, pluginName)
+		if err != nil {
+			return nil, err
+		}
+
+		pluginPaths[pluginName] = pluginPath
+		plugins = append(plugins, pluginName+"-"+pluginVersion)
+	}
+
+	if err := s.swapPaper(paperPath, folder); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(folder+"plugins", 0755); err != nil {
+		return nil, err
+	}
+	for _, plugin := range server.Plugins {
+		pluginName := strings.Split(plugin, "-")[0]
+		if _, ok := pluginPaths[pluginName]; ok {
+			continue
+		}
+
+		if err := os.Remove(folder + "plugins/" + pluginName + ".jar"); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	for pluginName, pluginPath := range pluginPaths {
+		if err := replaceFile(pluginPath, folder+"plugins/"+pluginName+".jar"); err != nil {
+			return nil, err
+		}
+	}
+
+	updatedAt := time.Now().UTC()
+	server.Paper = paperVersion
+	server.Plugins = plugins
+	server.UpdatedAt = &updatedAt
+	if err := saveServer(folder, server); err != nil {
+		return nil, err
+	}
+
+	return &server, nil
+}
+
+func (s *MegaServerService) swapPaper(paperPath, folder string) error {
+	bundle, err := os.MkdirTemp(s.paths.StagingPath, "paper-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(bundle)
+
+	if err := extractRar(paperPath, bundle); err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(bundle, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		relative, err := filepath.Rel(bundle, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(folder, relative)
+		if strings.HasSuffix(entry.Name(), ".jar") {
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			return replaceFile(path, target)
+		}
+
+		if _, err := os.Stat(target); !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		return copyBlob(path, target)
+	})
+}
+
+func (s *MegaServerService) DeleteMegaServer(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := deleteServer(s.paths.MegaServersPath, models.ServerMega, name, s.supervisor, s.ports); err != nil {
+		return err
+	}
+	s.changed()
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"github.com/mineleaguedev/luximo/models"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type MiniServerService struct {
+	paths      models.Paths
+	config     models.ServerConfig
+	supervisor *SupervisorService
+	ports      *PortAllocator
+	changed    func()
+	mutex      sync.Mutex
+}
+
+func NewMiniServerService(paths models.Paths, config models.Config, supervisor *SupervisorService, ports *PortAllocator, changed func()) *MiniServerService {
+	return &MiniServerService{paths: paths, config: config.Mini, supervisor: supervisor, ports: ports, changed: changed}
+}
+
+func (s *MiniServerService) CreateMiniServer(request models.CreateServerRequest) (*models.Server, error) {
+	if err := validateNames(request.Minigame, request.Format, request.Map); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	server, err := newServer(s.paths, models.ServerMini, request.Minigame+"-"+request.Format, request, s.ports)
+	if err != nil {
+		return nil, err
+	}
+
+	plugins := append(append([]string{}, s.config.Plugins...), s.config.Minigames[request.Minigame]...)
+	world := request.Minigame + "/" + request.Format + "/" + request.Map
+	err = provisionServer(s.paths, s.paths.MiniServersPath, &server, plugins, world, s.config.Properties, func(folder, mapFolder string) error {
+		mapConfig := filepath.Join(folder, filepath.FromSlash(s.config.MapConfig))
+		if err := os.MkdirAll(filepath.Dir(mapConfig), 0755); err != nil {
+			return err
+		}
+
+		return copyBlob(mapFolder+"map.yml", mapConfig)
+	})
+	if err != nil {
+		s.ports.Release(models.ServerMini, server.Name)
+		return nil, err
+	}
+	s.changed()
+
+	return &server, nil
+}
+
+func (s *MiniServerService) GetMiniServers() ([]models.Server, error) {
+	return loadServers(s.paths.MiniServersPath)
+}
+
+func (s *MiniServerService) DeleteMiniServer(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := deleteServer(s.paths.MiniServersPath, models.ServerMini, name, s.supervisor, s.ports); err != nil {
+		return err
+	}
+	s.changed()
+
+	return nil
+}
+
+func (s *MiniServerService) cleanup(name string) {
+	if err := s.DeleteMiniServer(name); err != nil {
+		log.Printf("Error cleaning up mini server %s: %s", name, err.Error())
+		return
+	}
+
+	log.Printf("Deleted mini server %s after its game ended", name)
+}
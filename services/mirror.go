@@ -0,0 +1,207 @@
+package services
+
+import (
+	"github.com/mineleaguedev/luximo/models"
+	"os"
+	"strings"
+)
+
+type MirrorService struct {
+	paths models.Paths
+}
+
+func NewMirrorService(paths models.Paths) *MirrorService {
+	return &MirrorService{paths: paths}
+}
+
+func (s *MirrorService) GetMirrorPlugins() (*models.PluginsResponse, error) {
+	plugins, err := os.ReadDir(s.paths.PluginsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.PluginsResponse{Success: true, Plugins: []models.Plugin{}}
+	for _, plugin := range plugins {
+		if plugin.IsDir() || !strings.HasSuffix(plugin.Name(), ".jar") {
+			continue
+		}
+
+		pluginFileName := strings.Split(strings.TrimSuffix(plugin.Name(), ".jar"), "-")
+		if len(pluginFileName) != 2 {
+			continue
+		}
+
+		response.Plugins = append(response.Plugins, models.Plugin{
+			Name:     pluginFileName[0],
+			Versions: []string{pluginFileName[1]},
+		})
+	}
+
+	return response, nil
+}
+
+func (s *MirrorService) GetMirrorPluginFile(pluginName, version string) (string, error) {
+	if err := validateNames(pluginName, version); err != nil {
+		return "", err
+	}
+
+	return existingFile(s.paths.PluginsPath + pluginName + "-" + version + ".jar")
+}
+
+func (s *MirrorService) GetMirrorMaps() (*models.MapsResponse, error) {
+	minigames, err := os.ReadDir(s.paths.MapsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.MapsResponse{Success: true, MiniGames: []models.MiniGames{}}
+	for _, minigame := range minigames {
+		if !minigame.IsDir() {
+			continue
+		}
+
+		formats, err := os.ReadDir(s.paths.MapsPath + minigame.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		minigameInfo := models.MiniGames{Name: minigame.Name(), Formats: []models.Format{}}
+		for _, format := range formats {
+			if !format.IsDir() {
+				continue
+			}
+
+			maps, err := os.ReadDir(s.paths.MapsPath + minigame.Name() + "/" + format.Name())
+			if err != nil {
+				return nil, err
+			}
+
+			formatInfo := models.Format{Format: format.Name(), Maps: []models.Map{}}
+			for _, mapVersion := range maps {
+				mapVersionFolderName := strings.Split(mapVersion.Name(), "-")
+				if !mapVersion.IsDir() || len(mapVersionFolderName) != 2 {
+					continue
+				}
+
+				mapFolder := s.paths.MapsPath + minigame.Name() + "/" + format.Name() + "/" + mapVersion.Name() + "/"
+				if _, err := os.Stat(mapFolder + "world.rar"); err != nil {
+					continue
+				}
+				if _, err := os.Stat(mapFolder + "map.yml"); err != nil {
+					continue
+				}
+
+				formatInfo.Maps = append(formatInfo.Maps, models.Map{
+					Name:     mapVersionFolderName[0],
+					Versions: []string{mapVersionFolderName[1]},
+				})
+			}
+
+			if len(formatInfo.Maps) > 0 {
+				minigameInfo.Formats = append(minigameInfo.Formats, formatInfo)
+			}
+		}
+
+		if len(minigameInfo.Formats) > 0 {
+			response.MiniGames = append(response.MiniGames, minigameInfo)
+		}
+	}
+
+	return response, nil
+}
+
+func (s *MirrorService) GetMirrorMapFile(minigame, format, mapName, version, file string) (string, error) {
+	if err := validateNames(minigame, format, mapName, version); err != nil {
+		return "", err
+	}
+
+	mapFolder := s.paths.MapsPath + minigame + "/" + format + "/" + mapName + "-" + version + "/"
+	switch file {
+	case "world":
+		return existingFile(mapFolder + "world.rar")
+	case "config":
+		return existingFile(mapFolder + "map.yml")
+	}
+
+	return "", NewError(models.ErrorNotFound, "unknown map file "+file, nil)
+}
+
+func (s *MirrorService) GetMirrorPaper() (*models.PaperResponse, error) {
+	versions, err := bundleVersions(s.paths.PaperPath, "paper-")
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, NewError(models.ErrorNotFound, "no paper versions available", nil)
+	}
+
+	return &models.PaperResponse{Success: true, Versions: versions}, nil
+}
+
+func (s *MirrorService) GetMirrorPaperFile(version string) (string, error) {
+	if err := validateNames(version); err != nil {
+		return "", err
+	}
+
+	return existingFile(s.paths.PaperPath + "paper-" + version + ".rar")
+}
+
+func (s *MirrorService) GetMirrorVelocity() (*models.VelocityResponse, error) {
+	versions, err := bundleVersions(s.paths.VelocityPath, "velocity-")
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, NewError(models.ErrorNotFound, "no velocity versions available", nil)
+	}
+
+	return &models.VelocityResponse{Success: true, Versions: versions}, nil
+}
+
+func (s *MirrorService) GetMirrorVelocityFile(version string) (string, error) {
+	if err := validateNames(version); err != nil {
+		return "", err
+	}
+
+	return existingFile(s.paths.VelocityPath + "velocity-" + version + ".rar")
+}
+
+func bundleVersions(path, prefix string) ([]string, error) {
+	bundles, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []string{}
+	for _, bundle := range bundles {
+		if bundle.IsDir() || !strings.HasPrefix(bundle.Name(), prefix) || !strings.HasSuffix(bundle.Name(), ".rar") {
+			continue
+		}
+
+		versions = append(versions, strings.TrimSuffix(strings.TrimPrefix(bundle.Name(), prefix), ".rar"))
+	}
+
+	return versions, nil
+}
+
+func existingFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) || (err == nil && info.IsDir()) {
+		return "", NewError(models.ErrorNotFound, "file not found", nil)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func validateNames(names ...string) error {
+	for _, name := range names {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+			return NewError(models.ErrorInvalidRequest, "invalid name "+name, nil)
+		}
+	}
+
+	return nil
+}
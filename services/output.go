@@ -0,0 +1,59 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const outputTailLines = 20
+
+type outputTail struct {
+	mutex   sync.Mutex
+	lines   []string
+	partial string
+	ready   *regexp.Regexp
+	onReady func()
+}
+
+func (t *outputTail) Write(p []byte) (int, error) {
+	t.mutex.Lock()
+
+	ready := false
+	lines := strings.Split(t.partial+string(p), "\n")
+	t.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		line = strings.TrimSuffix(line, "\r")
+		t.lines = append(t.lines, line)
+
+		if t.ready != nil && t.ready.MatchString(line) {
+			t.ready = nil
+			ready = true
+		}
+	}
+	if len(t.lines) > outputTailLines {
+		t.lines = append([]string{}, t.lines[len(t.lines)-outputTailLines:]...)
+	}
+	t.mutex.Unlock()
+
+	if ready {
+		t.onReady()
+	}
+
+	return len(p), nil
+}
+
+func (t *outputTail) Lines() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	lines := append([]string{}, t.lines...)
+	if t.partial != "" {
+		lines = append(lines, t.partial)
+	}
+	if len(lines) > outputTailLines {
+		lines = lines[len(lines)-outputTailLines:]
+	}
+
+	return lines
+}
@@ -1,30 +1,65 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"github.com/hashicorp/go-version"
 	"github.com/mineleaguedev/luximo/models"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"sort"
 	"strings"
 )
 
 type PaperService struct {
-	paths models.Paths
+	paths    models.Paths
+	client   *Client
+	listings *ListingCache
+	blobs    *BlobStore
 }
 
-func NewPaperService(paths models.Paths) *PaperService {
-	return &PaperService{paths: paths}
+func NewPaperService(paths models.Paths, client *Client, listings *ListingCache, blobs *BlobStore) *PaperService {
+	return &PaperService{paths: paths, client: client, listings: listings, blobs: blobs}
 }
 
-func (s *PaperService) UpdatePaper() error {
-	paperInfo, err := s.GetPaperVersionsInfo()
+func (s *PaperService) UpdatePaper(ctx context.Context, tracker Tracker) error {
+	paperInfo, listing, err := s.getPaperVersionsInfo(ctx)
 	if err != nil {
 		return err
 	}
+	tracker.ListingFetched(len(paperInfo.Versions), listing.NotModified)
+
+	if listing.Stale {
+		tracker.Stale(listing.FetchedAt)
+		return nil
+	}
+
+	if listing.NotModified {
+		return nil
+	}
+
+	if err := s.syncPaper(ctx, tracker, paperInfo); err != nil {
+		return err
+	}
+
+	return s.listings.MarkSynced("paper")
+}
+
+func (s *PaperService) syncPaper(ctx context.Context, tracker Tracker, paperInfo *models.PaperResponse) error {
+	lastVersion := paperInfo.LastVersion
+	checksum := paperInfo.Checksums[lastVersion]
+	size := paperInfo.Sizes[lastVersion]
+	if !s.blobs.Has("paper/"+lastVersion, checksum) {
+		var err error
+		size, err = artifactSize(ctx, s.client, size, "/paper/"+lastVersion)
+		if err != nil {
+			return err
+		}
+
+		if err := s.blobs.EnsureSpace(size); err != nil {
+			return err
+		}
+	}
 
 	paperVersions, err := os.ReadDir(s.paths.PaperPath)
 	if err != nil {
@@ -36,18 +71,10 @@ func (s *PaperService) UpdatePaper() error {
 			if err := os.RemoveAll(s.paths.PaperPath + paperVersion.Name()); err != nil {
 				return err
 			}
+			tracker.Removed(paperVersion.Name())
 		}
 
-		paperFileBytes, err := s.DownloadPaper(paperInfo.LastVersion)
-		if err != nil {
-			return err
-		}
-
-		if err := s.UpdatePaperVersion(paperInfo.LastVersion, *paperFileBytes); err != nil {
-			return err
-		}
-
-		return nil
+		return s.installPaper(ctx, tracker, lastVersion, checksum, size)
 	}
 
 	for _, paperVersion := range paperVersions {
@@ -55,6 +82,7 @@ func (s *PaperService) UpdatePaper() error {
 			if err := os.RemoveAll(s.paths.PaperPath + paperVersion.Name()); err != nil {
 				return err
 			}
+			tracker.Removed(paperVersion.Name())
 			continue
 		}
 
@@ -62,92 +90,109 @@ func (s *PaperService) UpdatePaper() error {
 		paperVersion := strings.ReplaceAll(paperFileName[1], ".rar", "")
 
 		if paperVersion != paperInfo.LastVersion {
-			paperFileBytes, err := s.DownloadPaper(paperInfo.LastVersion)
-			if err != nil {
-				return err
-			}
+			return s.installPaper(ctx, tracker, lastVersion, checksum, size)
+		}
+	}
 
-			if err := s.UpdatePaperVersion(paperInfo.LastVersion, *paperFileBytes); err != nil {
-				return err
-			}
+	return s.installPaper(ctx, tracker, lastVersion, checksum, size)
+}
+
+func (s *PaperService) installPaper(ctx context.Context, tracker Tracker, version, expected string, size int64) error {
+	tracker.Planned(1)
 
-			return nil
+	if sum, ok := s.blobs.Lookup("paper/"+version, expected); ok {
+		if err := s.blobs.Link(sum, s.paths.PaperPath+"paper-"+version+".rar"); err != nil {
+			return err
 		}
+		tracker.Installed("paper-" + version)
+		return nil
 	}
 
-	paperFileBytes, err := s.DownloadPaper(paperInfo.LastVersion)
+	size, err := artifactSize(ctx, s.client, size, "/paper/"+version)
 	if err != nil {
 		return err
 	}
 
-	if err := s.UpdatePaperVersion(paperInfo.LastVersion, *paperFileBytes); err != nil {
+	if err := s.blobs.EnsureSpace(size); err != nil {
 		return err
 	}
 
+	paperFileBytes, err := s.DownloadPaper(ctx, tracker, version)
+	if err != nil {
+		return err
+	}
+
+	if err := s.blobs.Verify("paper-"+version, expected, *paperFileBytes); err != nil {
+		return err
+	}
+
+	if err := s.UpdatePaperVersion(version, *paperFileBytes); err != nil {
+		return err
+	}
+
+	tracker.Installed("paper-" + version)
 	return nil
 }
 
-func (s *PaperService) GetPaperVersionsInfo() (*models.PaperResponse, error) {
-	resp, err := http.Get("https://api.mineleague.ru/paper")
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+func (s *PaperService) GetPaperVersionsInfo(ctx context.Context) (*models.PaperResponse, error) {
+	paperInfo, _, err := s.getPaperVersionsInfo(ctx)
+	return paperInfo, err
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+func (s *PaperService) getPaperVersionsInfo(ctx context.Context) (*models.PaperResponse, *Listing, error) {
+	listing, err := s.listings.Fetch(ctx, "paper", "/paper")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var response models.PaperResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, err
+	if err := json.Unmarshal(listing.Body, &response); err != nil {
+		return nil, nil, NewError(models.ErrorUpstreamRejected, "invalid response from API", err)
 	}
 
 	if !response.Success {
-		return nil, errors.New("error getting paper versions list from API")
+		return nil, nil, NewError(models.ErrorUpstreamRejected, "error getting paper versions list from API", nil)
+	}
+
+	if len(response.Versions) == 0 {
+		return nil, nil, NewError(models.ErrorUpstreamRejected, "no paper versions available from API", nil)
 	}
 
 	versions := make([]*version.Version, len(response.Versions))
 	for i, raw := range response.Versions {
-		v, _ := version.NewVersion(raw)
+		v, err := version.NewVersion(raw)
+		if err != nil {
+			return nil, nil, NewError(models.ErrorUpstreamRejected, "invalid paper version "+raw+" from API", err)
+		}
 		versions[i] = v
 	}
 	sort.Sort(version.Collection(versions))
 	response.LastVersion = versions[len(versions)-1].String()
 
-	return &response, nil
+	return &response, listing, nil
 }
 
-func (s *PaperService) DownloadPaper(version string) (*[]byte, error) {
-	resp, err := http.Get("https://api.mineleague.ru/paper/" + version)
+func (s *PaperService) DownloadPaper(ctx context.Context, tracker Tracker, version string) (*[]byte, error) {
+	resp, err := s.client.Get(ctx, "/paper/"+version)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, errors.New("error downloading paper from API")
+		return nil, upstreamStatusError("error downloading paper from API", resp)
 	}
 
-	fileBytes, err := ioutil.ReadAll(resp.Body)
+	tracker.DownloadStarted("paper-"+version, resp.ContentLength)
+	fileBytes, err := ioutil.ReadAll(&progressReader{reader: resp.Body, name: "paper-" + version, tracker: tracker})
 	if err != nil {
 		return nil, err
 	}
+	tracker.DownloadFinished("paper-" + version)
 
 	return &fileBytes, nil
 }
 
 func (s *PaperService) UpdatePaperVersion(version string, paperFileBytes []byte) error {
-	file, err := os.Create(s.paths.PaperPath + "paper-" + version + ".rar")
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	if _, err = file.Write(paperFileBytes); err != nil {
-		return err
-	}
-
-	return nil
+	return s.blobs.Install("paper/"+version, s.paths.PaperPath+"paper-"+version+".rar", paperFileBytes)
 }
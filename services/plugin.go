@@ -1,32 +1,52 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"github.com/hashicorp/go-version"
 	"github.com/mineleaguedev/luximo/models"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"sort"
 	"strings"
 )
 
 type PluginService struct {
-	paths models.Paths
+	paths    models.Paths
+	client   *Client
+	listings *ListingCache
+	blobs    *BlobStore
 }
 
-func NewPluginService(paths models.Paths) *PluginService {
-	return &PluginService{paths: paths}
+func NewPluginService(paths models.Paths, client *Client, listings *ListingCache, blobs *BlobStore) *PluginService {
+	return &PluginService{paths: paths, client: client, listings: listings, blobs: blobs}
 }
 
-func (s *PluginService) UpdatePlugins() error {
-	var pluginsArr []models.Plugin
-
-	pluginsInfo, err := s.GetPluginsInfo()
+func (s *PluginService) UpdatePlugins(ctx context.Context, tracker Tracker) error {
+	pluginsInfo, listing, err := s.getPluginsInfo(ctx)
 	if err != nil {
 		return err
 	}
+	tracker.ListingFetched(len(pluginsInfo), listing.NotModified)
+
+	if listing.Stale {
+		tracker.Stale(listing.FetchedAt)
+		return nil
+	}
+
+	if listing.NotModified {
+		return nil
+	}
+
+	if err := s.syncPlugins(ctx, tracker, pluginsInfo); err != nil {
+		return err
+	}
+
+	return s.listings.MarkSynced("plugin")
+}
+
+func (s *PluginService) syncPlugins(ctx context.Context, tracker Tracker, pluginsInfo []models.Plugin) error {
+	var pluginsArr []models.Plugin
 
 	plugins, err := os.ReadDir(s.paths.PluginsPath)
 	if err != nil {
@@ -34,11 +54,11 @@ func (s *PluginService) UpdatePlugins() error {
 	}
 
 	if len(plugins) == 0 {
-		if err := s.deleteOldAndWrongPlugins(pluginsArr, pluginsInfo); err != nil {
+		if err := s.deleteOldAndWrongPlugins(tracker, pluginsArr, pluginsInfo); err != nil {
 			return err
 		}
 
-		if err := s.addNewPlugins(pluginsInfo); err != nil {
+		if err := s.addNewPlugins(ctx, tracker, pluginsInfo); err != nil {
 			return err
 		}
 
@@ -50,6 +70,7 @@ func (s *PluginService) UpdatePlugins() error {
 			if err := os.RemoveAll(s.paths.PluginsPath + plugin.Name()); err != nil {
 				return err
 			}
+			tracker.Removed(plugin.Name())
 			continue
 		}
 
@@ -64,18 +85,18 @@ func (s *PluginService) UpdatePlugins() error {
 		})
 	}
 
-	if err := s.deleteOldAndWrongPlugins(pluginsArr, pluginsInfo); err != nil {
+	if err := s.deleteOldAndWrongPlugins(tracker, pluginsArr, pluginsInfo); err != nil {
 		return err
 	}
 
-	if err := s.addNewPlugins(pluginsInfo); err != nil {
+	if err := s.addNewPlugins(ctx, tracker, pluginsInfo); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (s *PluginService) deleteOldAndWrongPlugins(plugins, pluginsInfo []models.Plugin) error {
+func (s *PluginService) deleteOldAndWrongPlugins(tracker Tracker, plugins, pluginsInfo []models.Plugin) error {
 	for _, plugin := range plugins {
 		var hasPlugin bool
 		for _, pluginInfo := range pluginsInfo {
@@ -88,55 +109,102 @@ func (s *PluginService) deleteOldAndWrongPlugins(plugins, pluginsInfo []models.P
 			if err := os.RemoveAll(s.paths.PluginsPath + plugin.Name + "-" + plugin.LastVersion + ".jar"); err != nil {
 				return err
 			}
+			tracker.Removed(plugin.Name + "-" + plugin.LastVersion + ".jar")
 		}
 	}
 
 	return nil
 }
 
-func (s *PluginService) addNewPlugins(pluginsInfo []models.Plugin) error {
+func (s *PluginService) addNewPlugins(ctx context.Context, tracker Tracker, pluginsInfo []models.Plugin) error {
+	var newPlugins []models.Plugin
 	for _, pluginInfo := range pluginsInfo {
 		_, err := os.Stat(s.paths.PluginsPath + pluginInfo.Name + "-" + pluginInfo.LastVersion + ".jar")
 		if os.IsNotExist(err) {
-			pluginFileBytes, err := s.DownloadPlugin(pluginInfo.Name, pluginInfo.LastVersion)
-			if err != nil {
-				return err
-			}
+			newPlugins = append(newPlugins, pluginInfo)
+		}
+	}
+	tracker.Planned(len(newPlugins))
+
+	var required int64
+	for _, pluginInfo := range newPlugins {
+		if s.blobs.Has("plugin/"+pluginInfo.Name+"/"+pluginInfo.LastVersion, pluginInfo.Checksums[pluginInfo.LastVersion]) {
+			continue
+		}
+
+		size, err := artifactSize(ctx, s.client, pluginInfo.Sizes[pluginInfo.LastVersion], "/plugin/"+pluginInfo.Name+"/"+pluginInfo.LastVersion)
+		if err != nil {
+			return err
+		}
+		required += size
+	}
+
+	if err := s.blobs.EnsureSpace(required); err != nil {
+		return err
+	}
 
-			if err := s.UpdatePlugin(pluginInfo.Name, pluginInfo.LastVersion, *pluginFileBytes); err != nil {
+	for _, pluginInfo := range newPlugins {
+		key := "plugin/" + pluginInfo.Name + "/" + pluginInfo.LastVersion
+		target := s.paths.PluginsPath + pluginInfo.Name + "-" + pluginInfo.LastVersion + ".jar"
+		expected := pluginInfo.Checksums[pluginInfo.LastVersion]
+
+		if sum, ok := s.blobs.Lookup(key, expected); ok {
+			if err := s.blobs.Link(sum, target); err != nil {
 				return err
 			}
+			tracker.Installed(pluginInfo.Name + "-" + pluginInfo.LastVersion + ".jar")
+			continue
+		}
+
+		pluginFileBytes, err := s.DownloadPlugin(ctx, tracker, pluginInfo.Name, pluginInfo.LastVersion)
+		if err != nil {
+			return err
+		}
+
+		if err := s.blobs.Verify(pluginInfo.Name+"-"+pluginInfo.LastVersion+".jar", expected, *pluginFileBytes); err != nil {
+			return err
 		}
+
+		if err := s.UpdatePlugin(pluginInfo.Name, pluginInfo.LastVersion, *pluginFileBytes); err != nil {
+			return err
+		}
+		tracker.Installed(pluginInfo.Name + "-" + pluginInfo.LastVersion + ".jar")
 	}
 
 	return nil
 }
 
-func (s *PluginService) GetPluginsInfo() ([]models.Plugin, error) {
-	resp, err := http.Get("https://api.mineleague.ru/plugin")
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+func (s *PluginService) GetPluginsInfo(ctx context.Context) ([]models.Plugin, error) {
+	pluginsInfo, _, err := s.getPluginsInfo(ctx)
+	return pluginsInfo, err
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+func (s *PluginService) getPluginsInfo(ctx context.Context) ([]models.Plugin, *Listing, error) {
+	listing, err := s.listings.Fetch(ctx, "plugin", "/plugin")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var response models.PluginsResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, err
+	if err := json.Unmarshal(listing.Body, &response); err != nil {
+		return nil, nil, NewError(models.ErrorUpstreamRejected, "invalid response from API", err)
 	}
 
 	if !response.Success {
-		return nil, errors.New("error getting plugins list from API")
+		return nil, nil, NewError(models.ErrorUpstreamRejected, "error getting plugins list from API", nil)
 	}
 
 	for index, plugin := range response.Plugins {
+		if len(plugin.Versions) == 0 {
+			return nil, nil, NewError(models.ErrorUpstreamRejected, "no versions of plugin "+plugin.Name+" available from API", nil)
+		}
+
 		versions := make([]*version.Version, len(plugin.Versions))
 		for i, raw := range plugin.Versions {
-			v, _ := version.NewVersion(raw)
+			v, err := version.NewVersion(raw)
+			if err != nil {
+				return nil, nil, NewError(models.ErrorUpstreamRejected, "invalid version "+raw+" of plugin "+plugin.Name+" from API", err)
+			}
 			versions[i] = v
 		}
 		sort.Sort(version.Collection(versions))
@@ -144,38 +212,30 @@ func (s *PluginService) GetPluginsInfo() ([]models.Plugin, error) {
 		response.Plugins[index] = plugin
 	}
 
-	return response.Plugins, nil
+	return response.Plugins, listing, nil
 }
 
-func (s *PluginService) DownloadPlugin(pluginName, version string) (*[]byte, error) {
-	resp, err := http.Get("https://api.mineleague.ru/plugin/" + pluginName + "/" + version)
+func (s *PluginService) DownloadPlugin(ctx context.Context, tracker Tracker, pluginName, version string) (*[]byte, error) {
+	resp, err := s.client.Get(ctx, "/plugin/"+pluginName+"/"+version)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, errors.New("error downloading plugin from API")
+		return nil, upstreamStatusError("error downloading plugin from API", resp)
 	}
 
-	fileBytes, err := ioutil.ReadAll(resp.Body)
+	tracker.DownloadStarted(pluginName+"-"+version+".jar", resp.ContentLength)
+	fileBytes, err := ioutil.ReadAll(&progressReader{reader: resp.Body, name: pluginName + "-" + version + ".jar", tracker: tracker})
 	if err != nil {
 		return nil, err
 	}
+	tracker.DownloadFinished(pluginName + "-" + version + ".jar")
 
 	return &fileBytes, nil
 }
 
 func (s *PluginService) UpdatePlugin(pluginName, version string, pluginFileBytes []byte) error {
-	file, err := os.Create(s.paths.PluginsPath + pluginName + "-" + version + ".jar")
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	if _, err = file.Write(pluginFileBytes); err != nil {
-		return err
-	}
-
-	return nil
+	return s.blobs.Install("plugin/"+pluginName+"/"+version, s.paths.PluginsPath+pluginName+"-"+version+".jar", pluginFileBytes)
 }
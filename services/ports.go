@@ -0,0 +1,244 @@
+package services
+
+import (
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+type PortAllocator struct {
+	paths       models.Paths
+	ranges      map[string]models.PortRange
+	mutex       sync.Mutex
+	assignments map[string]models.PortAssignment
+}
+
+func NewPortAllocator(paths models.Paths, config models.Config) *PortAllocator {
+	a := &PortAllocator{paths: paths, ranges: config.Ports, assignments: make(map[string]models.PortAssignment)}
+
+	if err := a.load(); err != nil {
+		log.Printf("Error loading port assignments: %s", err.Error())
+	}
+	if err := a.adopt(); err != nil {
+		log.Printf("Error adopting port assignments: %s", err.Error())
+	}
+
+	return a
+}
+
+func (a *PortAllocator) Allocate(serverType, name string, requested int) (models.PortAssignment, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	assignment := models.PortAssignment{Type: serverType, Name: name}
+	for _, existing := range a.assignments {
+		if existing.Name == name {
+			return assignment, NewError(models.ErrorConflict, existing.Type+" server "+name+" already exists", nil)
+		}
+	}
+	taken := a.taken()
+
+	if requested != 0 {
+		if requested < 1 || requested > 65535 {
+			return assignment, NewError(models.ErrorInvalidRequest, "invalid port "+strconv.Itoa(requested), nil)
+		}
+		if taken[requested] || !portFree("tcp", requested) {
+			return assignment, NewError(models.ErrorConflict, "port "+strconv.Itoa(requested)+" is already in use", nil)
+		}
+		assignment.Server = requested
+	} else {
+		port, err := a.pick(serverType, "tcp", taken)
+		if err != nil {
+			return assignment, err
+		}
+		assignment.Server = port
+	}
+	taken[assignment.Server] = true
+
+	if serverType != models.ServerProxy {
+		port, err := a.pick("rcon", "tcp", taken)
+		if err != nil {
+			return assignment, err
+		}
+		assignment.RCON = port
+		taken[port] = true
+	}
+
+	port, err := a.pick("query", "udp", taken)
+	if err != nil {
+		return assignment, err
+	}
+	assignment.Query = port
+
+	a.assignments[serverType+"/"+name] = assignment
+	if err := a.save(); err != nil {
+		delete(a.assignments, serverType+"/"+name)
+		return assignment, err
+	}
+
+	return assignment, nil
+}
+
+func (a *PortAllocator) Release(serverType, name string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if _, ok := a.assignments[serverType+"/"+name]; !ok {
+		return nil
+	}
+
+	delete(a.assignments, serverType+"/"+name)
+	return a.save()
+}
+
+func (a *PortAllocator) GetPorts() []models.PortAssignment {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.sorted()
+}
+
+func (a *PortAllocator) pick(kind, network string, taken map[int]bool) (int, error) {
+	portRange, ok := a.ranges[kind]
+	if !ok || portRange.From <= 0 || portRange.To < portRange.From {
+		return 0, NewError(models.ErrorInvalidConfig, "no port range configured for "+kind, nil)
+	}
+
+	for port := portRange.From; port <= portRange.To; port++ {
+		if !taken[port] && portFree(network, port) {
+			return port, nil
+		}
+	}
+
+	return 0, NewError(models.ErrorConflict, "no free "+kind+" ports left in "+strconv.Itoa(portRange.From)+"-"+strconv.Itoa(portRange.To), nil)
+}
+
+func (a *PortAllocator) taken() map[int]bool {
+	taken := make(map[int]bool)
+	for _, assignment := range a.assignments {
+		taken[assignment.Server] = true
+		taken[assignment.RCON] = true
+		taken[assignment.Query] = true
+	}
+	delete(taken, 0)
+
+	return taken
+}
+
+func (a *PortAllocator) adopt() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	existing := make(map[string]bool)
+	changed := false
+	for _, serverType := range []string{models.ServerLobby, models.ServerMini, models.ServerMega, models.ServerProxy} {
+		root, err := serverRoot(a.paths, serverType)
+		if err != nil {
+			return err
+		}
+
+		servers, err := loadServers(root)
+		if err != nil {
+			return err
+		}
+
+		for _, server := range servers {
+			existing[serverType+"/"+server.Name] = true
+			if _, ok := a.assignments[serverType+"/"+server.Name]; ok {
+				continue
+			}
+
+			a.assignments[serverType+"/"+server.Name] = models.PortAssignment{
+				Type:   serverType,
+				Name:   server.Name,
+				Server: server.Port,
+				RCON:   server.RCONPort,
+				Query:  server.QueryPort,
+			}
+			changed = true
+		}
+	}
+
+	for key := range a.assignments {
+		if !existing[key] {
+			delete(a.assignments, key)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return a.save()
+}
+
+func (a *PortAllocator) sorted() []models.PortAssignment {
+	assignments := make([]models.PortAssignment, 0, len(a.assignments))
+	for _, assignment := range a.assignments {
+		assignments = append(assignments, assignment)
+	}
+	sort.Slice(assignments, func(i, j int) bool {
+		if assignments[i].Type != assignments[j].Type {
+			return assignments[i].Type < assignments[j].Type
+		}
+		return assignments[i].Name < assignments[j].Name
+	})
+
+	return assignments
+}
+
+func (a *PortAllocator) save() error {
+	assignmentsBytes, err := json.MarshalIndent(a.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeStaged(a.paths, a.paths.ServersPath+"ports.json", assignmentsBytes)
+}
+
+func (a *PortAllocator) load() error {
+	assignmentsBytes, err := os.ReadFile(a.paths.ServersPath + "ports.json")
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var assignments []models.PortAssignment
+	if err := json.Unmarshal(assignmentsBytes, &assignments); err != nil {
+		return err
+	}
+
+	for _, assignment := range assignments {
+		a.assignments[assignment.Type+"/"+assignment.Name] = assignment
+	}
+
+	return nil
+}
+
+func portFree(network string, port int) bool {
+	address := ":" + strconv.Itoa(port)
+
+	if network == "udp" {
+		conn, err := net.ListenPacket(network, address)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const serverMetadataFile = "luximo.json"
+
+func currentBundle(path, kind string) (string, string, error) {
+	versions, err := bundleVersions(path, kind+"-")
+	if err != nil {
+		return "", "", err
+	}
+
+	latest := ""
+	for _, bundleVersion := range versions {
+		if latest == "" || newerVersion(bundleVersion, latest) {
+			latest = bundleVersion
+		}
+	}
+	if latest == "" {
+		return "", "", NewError(models.ErrorNotFound, "no "+kind+" bundle installed", nil)
+	}
+
+	return latest, path + kind + "-" + latest + ".rar", nil
+}
+
+func installedPlugin(paths models.Paths, pluginName string) (string, string, error) {
+	if err := validateNames(pluginName); err != nil {
+		return "", "", err
+	}
+
+	plugins, err := os.ReadDir(paths.PluginsPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	latest := ""
+	for _, plugin := range plugins {
+		pluginFileName := strings.Split(strings.TrimSuffix(plugin.Name(), ".jar"), "-")
+		if plugin.IsDir() || !strings.HasSuffix(plugin.Name(), ".jar") || len(pluginFileName) != 2 || pluginFileName[0] != pluginName {
+			continue
+		}
+
+		if latest == "" || newerVersion(pluginFileName[1], latest) {
+			latest = pluginFileName[1]
+		}
+	}
+	if latest == "" {
+		return "", "", NewError(models.ErrorNotFound, "plugin "+pluginName+" is not installed", nil)
+	}
+
+	return latest, paths.PluginsPath + pluginName + "-" + latest + ".jar", nil
+}
+
+func installedMap(paths models.Paths, world string) (string, string, error) {
+	parts := strings.Split(world, "/")
+	if len(parts) != 3 {
+		return "", "", NewError(models.ErrorInvalidRequest, "invalid world "+world+", expected minigame/format/map", nil)
+	}
+	if err := validateNames(parts...); err != nil {
+		return "", "", err
+	}
+
+	formatFolder := paths.MapsPath + parts[0] + "/" + parts[1] + "/"
+	maps, err := os.ReadDir(formatFolder)
+	if err != nil && !os.IsNotExist(err) {
+		return "", "", err
+	}
+
+	latest := ""
+	for _, mapVersion := range maps {
+		mapVersionFolderName := strings.Split(mapVersion.Name(), "-")
+		if !mapVersion.IsDir() || len(mapVersionFolderName) != 2 || mapVersionFolderName[0] != parts[2] {
+			continue
+		}
+		if _, err := os.Stat(formatFolder + mapVersion.Name() + "/world.rar"); err != nil {
+			continue
+		}
+
+		if latest == "" || newerVersion(mapVersionFolderName[1], latest) {
+			latest = mapVersionFolderName[1]
+		}
+	}
+	if latest == "" {
+		return "", "", NewError(models.ErrorNotFound, "map "+world+" is not installed", nil)
+	}
+
+	return latest, formatFolder + parts[2] + "-" + latest + "/", nil
+}
+
+func installPlugins(paths models.Paths, folder string, pluginNames []string) ([]string, error) {
+	if err := os.MkdirAll(folder+"plugins", 0755); err != nil {
+		return nil, err
+	}
+
+	plugins := []string{}
+	for _, pluginName := range pluginNames {
+		pluginVersion, pluginPath, err := installedPlugin(paths, pluginName)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := copyBlob(pluginPath, folder+"plugins/"+pluginName+".jar"); err != nil {
+			return nil, err
+		}
+
+		plugins = append(plugins, pluginName+"-"+pluginVersion)
+	}
+
+	return plugins, nil
+}
+
+func replaceFile(source, target string) error {
+	part := target + ".part"
+	if err := os.Remove(part); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := copyBlob(source, part); err != nil {
+		return err
+	}
+
+	if err := os.Rename(part, target); err != nil {
+		os.Remove(part)
+		return err
+	}
+
+	return nil
+}
+
+func writeProperties(path string, properties map[string]string) error {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, key := range keys {
+		builder.WriteString(key + "=" + properties[key] + "\n")
+	}
+
+	return os.WriteFile(path, []byte(builder.String()), 0644)
+}
+
+func serverProperties(server models.Server, overrides map[string]string, rconPassword string) map[string]string {
+	properties := map[string]string{
+		"level-name":  "world",
+		"motd":        server.Name,
+		"online-mode": "false",
+	}
+	for key, value := range overrides {
+		properties[key] = value
+	}
+	properties["server-port"] = strconv.Itoa(server.Port)
+	if server.RCONPort != 0 {
+		properties["enable-rcon"] = "true"
+		properties["rcon.port"] = strconv.Itoa(server.RCONPort)
+		properties["rcon.password"] = rconPassword
+	}
+	if server.QueryPort != 0 {
+		properties["enable-query"] = "true"
+		properties["query.port"] = strconv.Itoa(server.QueryPort)
+	}
+
+	return properties
+}
+
+func newServer(paths models.Paths, serverType, prefix string, request models.CreateServerRequest, ports *PortAllocator) (models.Server, error) {
+	var servers []models.Server
+	for _, root := range []string{paths.LobbyServersPath, paths.MiniServersPath, paths.MegaServersPath, paths.ProxyServersPath} {
+		rootServers, err := loadServers(root)
+		if err != nil {
+			return models.Server{}, err
+		}
+		servers = append(servers, rootServers...)
+	}
+
+	server := models.Server{
+		Name:      request.Name,
+		Type:      serverType,
+		CreatedAt: time.Now().UTC(),
+	}
+	if server.Name == "" {
+		server.Name = nextServerName(prefix, servers)
+	}
+	if err := validateNames(server.Name); err != nil {
+		return server, err
+	}
+
+	for _, existing := range servers {
+		if existing.Name == server.Name {
+			return server, NewError(models.ErrorConflict, existing.Type+" server "+server.Name+" already exists", nil)
+		}
+	}
+
+	root, err := serverRoot(paths, serverType)
+	if err != nil {
+		return server, err
+	}
+	if _, err := os.Stat(root + server.Name); err == nil {
+		return server, NewError(models.ErrorConflict, serverType+" server "+server.Name+" already exists", nil)
+	}
+
+	assignment, err := ports.Allocate(serverType, server.Name, request.Port)
+	if err != nil {
+		return server, err
+	}
+	server.Port = assignment.Server
+	server.RCONPort = assignment.RCON
+	server.QueryPort = assignment.Query
+
+	return server, nil
+}
+
+func provisionServer(paths models.Paths, root string, server *models.Server, pluginNames []string, world string, properties map[string]string, mapHook func(folder, mapFolder string) error) error {
+	paperVersion, paperPath, err := currentBundle(paths.PaperPath, "paper")
+	if err != nil {
+		return err
+	}
+	server.Paper = paperVersion
+
+	rconPassword, err := newSecret()
+	if err != nil {
+		return err
+	}
+
+	return buildServer(paths, root+server.Name, func(folder string) error {
+		if err := extractRar(paperPath, folder); err != nil {
+			return err
+		}
+
+		plugins, err := installPlugins(paths, folder, pluginNames)
+		if err != nil {
+			return err
+		}
+		server.Plugins = plugins
+
+		if world != "" {
+			mapVersion, mapFolder, err := installedMap(paths, world)
+			if err != nil {
+				return err
+			}
+
+			if err := extractWorld(mapFolder+"world.rar", folder+"world"); err != nil {
+				return err
+			}
+			server.Map = world + "-" + mapVersion
+
+			if mapHook != nil {
+				if err := mapHook(folder, mapFolder); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := writeProperties(folder+"server.properties", serverProperties(*server, properties, rconPassword)); err != nil {
+			return err
+		}
+
+		return saveServer(folder, *server)
+	})
+}
+
+func deleteServer(root, serverType, name string, supervisor *SupervisorService, ports *PortAllocator) error {
+	if err := validateNames(name); err != nil {
+		return err
+	}
+	if supervisor.Running(serverType, name) {
+		return NewError(models.ErrorConflict, serverType+" server "+name+" is running", nil)
+	}
+
+	if _, err := loadServer(root + name + "/"); err != nil {
+		if os.IsNotExist(err) {
+			return NewError(models.ErrorNotFound, serverType+" server "+name+" not found", nil)
+		}
+		return err
+	}
+
+	if err := os.RemoveAll(root + name); err != nil {
+		return err
+	}
+	supervisor.Forget(serverType, name)
+
+	return ports.Release(serverType, name)
+}
+
+func serverRoot(paths models.Paths, serverType string) (string, error) {
+	switch serverType {
+	case models.ServerLobby:
+		return paths.LobbyServersPath, nil
+	case models.ServerMini:
+		return paths.MiniServersPath, nil
+	case models.ServerMega:
+		return paths.MegaServersPath, nil
+	case models.ServerProxy:
+		return paths.ProxyServersPath, nil
+	}
+
+	return "", NewError(models.ErrorInvalidRequest, "unknown server type "+serverType, nil)
+}
+
+func buildServer(paths models.Paths, target string, build func(folder string) error) error {
+	folder, err := os.MkdirTemp(paths.StagingPath, "server-*")
+	if err != nil {
+		return err
+	}
+
+	if err := build(folder + "/"); err != nil {
+		os.RemoveAll(folder)
+		return err
+	}
+
+	if err := os.Rename(folder, target); err != nil {
+		os.RemoveAll(folder)
+		return err
+	}
+
+	return nil
+}
+
+func saveServer(folder string, server models.Server) error {
+	serverBytes, err := json.MarshalIndent(server, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(folder+serverMetadataFile, serverBytes, 0644)
+}
+
+func loadServer(folder string) (models.Server, error) {
+	var server models.Server
+
+	serverBytes, err := os.ReadFile(folder + serverMetadataFile)
+	if err != nil {
+		return server, err
+	}
+
+	return server, json.Unmarshal(serverBytes, &server)
+}
+
+func loadServers(path string) ([]models.Server, error) {
+	folders, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := []models.Server{}
+	for _, folder := range folders {
+		if !folder.IsDir() {
+			continue
+		}
+
+		server, err := loadServer(path + folder.Name() + "/")
+		if err != nil {
+			continue
+		}
+
+		servers = append(servers, server)
+	}
+
+	return servers, nil
+}
+
+func nextServerName(prefix string, servers []models.Server) string {
+	taken := make(map[string]bool, len(servers))
+	for _, server := range servers {
+		taken[server.Name] = true
+	}
+
+	for i := 1; ; i++ {
+		name := prefix + "-" + strconv.Itoa(i)
+		if !taken[name] {
+			return name
+		}
+	}
+}
+
+func newSecret() (string, error) {
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
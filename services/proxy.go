@@ -0,0 +1,210 @@
+package services
+
+import (
+	"github.com/mineleaguedev/luximo/models"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type ProxyServerService struct {
+	paths      models.Paths
+	config     models.ServerConfig
+	supervisor *SupervisorService
+	ports      *PortAllocator
+	mutex      sync.Mutex
+}
+
+func NewProxyServerService(paths models.Paths, config models.Config, supervisor *SupervisorService, ports *PortAllocator) *ProxyServerService {
+	return &ProxyServerService{paths: paths, config: config.Proxy, supervisor: supervisor, ports: ports}
+}
+
+func (s *ProxyServerService) CreateProxyServer(request models.CreateServerRequest) (*models.Server, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	velocityVersion, velocityPath, err := currentBundle(s.paths.VelocityPath, "velocity")
+	if err != nil {
+		return nil, err
+	}
+
+	backends, err := s.backends()
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := newServer(s.paths, models.ServerProxy, models.ServerProxy, request, s.ports)
+	if err != nil {
+		return nil, err
+	}
+	server.Velocity = velocityVersion
+
+	err = buildServer(s.paths, s.paths.ProxyServersPath+server.Name, func(folder string) error {
+		if err := extractRar(velocityPath, folder); err != nil {
+			return err
+		}
+
+		plugins, err := installPlugins(s.paths, folder, s.config.Plugins)
+		if err != nil {
+			return err
+		}
+		server.Plugins = plugins
+
+		if err := writeStaged(s.paths, folder+"velocity.toml", s.velocityConfig(server, backends)); err != nil {
+			return err
+		}
+
+		return saveServer(folder, server)
+	})
+	if err != nil {
+		s.ports.Release(models.ServerProxy, server.Name)
+		return nil, err
+	}
+
+	return &server, nil
+}
+
+func (s *ProxyServerService) GetProxyServers() ([]models.Server, error) {
+	return loadServers(s.paths.ProxyServersPath)
+}
+
+func (s *ProxyServerService) UpdateProxyServers() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	proxies, err := loadServers(s.paths.ProxyServersPath)
+	if err != nil {
+		return err
+	}
+
+	backends, err := s.backends()
+	if err != nil {
+		return err
+	}
+
+	for _, proxy := range proxies {
+		if err := writeStaged(s.paths, s.paths.ProxyServersPath+proxy.Name+"/velocity.toml", s.velocityConfig(proxy, backends)); err != nil {
+			return err
+		}
+
+		reloaded, err := s.supervisor.SendCommand(models.ServerProxy, proxy.Name, "velocity reload")
+		if err != nil {
+			log.Printf("Error reloading proxy server %s: %s", proxy.Name, err.Error())
+		} else if reloaded {
+			log.Printf("Reloading proxy server %s with %d backends", proxy.Name, len(backends))
+		}
+	}
+
+	return nil
+}
+
+func (s *ProxyServerService) DeleteProxyServer(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return deleteServer(s.paths.ProxyServersPath, models.ServerProxy, name, s.supervisor, s.ports)
+}
+
+func (s *ProxyServerService) backends() ([]models.Server, error) {
+	var backends []models.Server
+	for _, path := range []string{s.paths.LobbyServersPath, s.paths.MiniServersPath, s.paths.MegaServersPath} {
+		servers, err := loadServers(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, server := range servers {
+			if s.supervisor.Ready(server.Type, server.Name) {
+				backends = append(backends, server)
+			}
+		}
+	}
+
+	return backends, nil
+}
+
+func (s *ProxyServerService) velocityConfig(proxy models.Server, backends []models.Server) []byte {
+	properties := map[string]string{
+		"config-version": "2.5",
+		"online-mode":    "true",
+	}
+	for key, value := range s.config.Properties {
+		properties[key] = value
+	}
+	properties["bind"] = "0.0.0.0:" + strconv.Itoa(proxy.Port)
+
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, key := range keys {
+		builder.WriteString(key + " = " + tomlValue(properties[key]) + "\n")
+	}
+
+	builder.WriteString("\n[servers]\n")
+	types := make(map[string][]string)
+	names := make(map[string]bool, len(backends))
+	for _, backend := range backends {
+		if names[backend.Name] {
+			continue
+		}
+		builder.WriteString(strconv.Quote(backend.Name) + " = " + strconv.Quote(s.config.BackendHost+":"+strconv.Itoa(backend.Port)) + "\n")
+		types[backend.Type] = append(types[backend.Type], backend.Name)
+		names[backend.Name] = true
+	}
+	builder.WriteString("try = " + tomlArray(types[models.ServerLobby]) + "\n")
+
+	hosts := make([]string, 0, len(s.config.ForcedHosts))
+	for host := range s.config.ForcedHosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	builder.WriteString("\n[forced-hosts]\n")
+	for _, host := range hosts {
+		var targets []string
+		for _, target := range s.config.ForcedHosts[host] {
+			if servers, ok := types[target]; ok {
+				targets = append(targets, servers...)
+			} else if names[target] {
+				targets = append(targets, target)
+			}
+		}
+		if len(targets) == 0 {
+			continue
+		}
+
+		builder.WriteString(strconv.Quote(host) + " = " + tomlArray(targets) + "\n")
+	}
+
+	if proxy.QueryPort != 0 {
+		builder.WriteString("\n[query]\nenabled = true\nport = " + strconv.Itoa(proxy.QueryPort) + "\n")
+	}
+
+	return []byte(builder.String())
+}
+
+func tomlValue(value string) string {
+	if value == "true" || value == "false" {
+		return value
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return value
+	}
+
+	return strconv.Quote(value)
+}
+
+func tomlArray(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, strconv.Quote(value))
+	}
+
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,426 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/hashicorp/go-version"
+	"github.com/mineleaguedev/luximo/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	zipMagic = []byte("PK\x03\x04")
+	rarMagic = []byte("Rar!\x1a\x07")
+)
+
+type RegistryService struct {
+	paths models.Paths
+	mutex sync.Mutex
+}
+
+func NewRegistryService(paths models.Paths) *RegistryService {
+	return &RegistryService{paths: paths}
+}
+
+func (s *RegistryService) PublishPlugin(pluginName, version string, pluginFileBytes []byte) (*models.PublishResponse, error) {
+	if err := validatePublish(version, pluginName); err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(pluginFileBytes, zipMagic) {
+		return nil, NewError(models.ErrorInvalidRequest, "plugin file is not a jar archive", nil)
+	}
+
+	folder := s.paths.RegistryPath + "plugin/" + pluginName + "/"
+	checksum, err := s.publishFile("plugin "+pluginName, folder, version+".jar", pluginFileBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PublishResponse{
+		Success:   true,
+		Kind:      "plugin",
+		Name:      pluginName,
+		Version:   version,
+		Checksums: map[string]string{"jar": checksum},
+	}, nil
+}
+
+func (s *RegistryService) PublishMap(minigame, format, mapName, version string, mapWorldFileBytes, mapConfigFileBytes []byte) (*models.PublishResponse, error) {
+	if err := validatePublish(version, minigame, format, mapName); err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(mapWorldFileBytes, rarMagic) {
+		return nil, NewError(models.ErrorInvalidRequest, "map world is not a rar archive", nil)
+	}
+	if len(bytes.TrimSpace(mapConfigFileBytes)) == 0 {
+		return nil, NewError(models.ErrorInvalidRequest, "map config is empty", nil)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	folder := s.paths.RegistryPath + "map/" + minigame + "/" + format + "/" + mapName + "/" + version + "/"
+	if _, err := os.Stat(folder); err == nil {
+		return nil, NewError(models.ErrorConflict, "map "+mapName+" version "+version+" is already published", nil)
+	}
+
+	if err := os.MkdirAll(s.paths.StagingPath, 0755); err != nil {
+		return nil, err
+	}
+	stagingFolder, err := os.MkdirTemp(s.paths.StagingPath, "map-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stagingFolder)
+
+	worldChecksum := checksum(mapWorldFileBytes)
+	configChecksum := checksum(mapConfigFileBytes)
+	files := map[string][]byte{
+		"world.rar":        mapWorldFileBytes,
+		"world.rar.sha256": []byte(worldChecksum),
+		"map.yml":          mapConfigFileBytes,
+		"map.yml.sha256":   []byte(configChecksum),
+	}
+	for name, fileBytes := range files {
+		if err := os.WriteFile(stagingFolder+"/"+name, fileBytes, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(s.paths.RegistryPath+"map/"+minigame+"/"+format+"/"+mapName, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(stagingFolder, strings.TrimSuffix(folder, "/")); err != nil {
+		return nil, err
+	}
+
+	return &models.PublishResponse{
+		Success:   true,
+		Kind:      "map",
+		Name:      minigame + "/" + format + "/" + mapName,
+		Version:   version,
+		Checksums: map[string]string{"world": worldChecksum, "config": configChecksum},
+	}, nil
+}
+
+func (s *RegistryService) PublishPaper(version string, paperFileBytes []byte) (*models.PublishResponse, error) {
+	return s.publishBundle("paper", version, paperFileBytes)
+}
+
+func (s *RegistryService) PublishVelocity(version string, velocityFileBytes []byte) (*models.PublishResponse, error) {
+	return s.publishBundle("velocity", version, velocityFileBytes)
+}
+
+func (s *RegistryService) publishBundle(kind, version string, fileBytes []byte) (*models.PublishResponse, error) {
+	if err := validatePublish(version); err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(fileBytes, rarMagic) {
+		return nil, NewError(models.ErrorInvalidRequest, kind+" bundle is not a rar archive", nil)
+	}
+
+	checksum, err := s.publishFile(kind, s.paths.RegistryPath+kind+"/", version+".rar", fileBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.PublishResponse{
+		Success:   true,
+		Kind:      kind,
+		Version:   version,
+		Checksums: map[string]string{"rar": checksum},
+	}, nil
+}
+
+func (s *RegistryService) publishFile(artifact, folder, name string, fileBytes []byte) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := os.Stat(folder + name); err == nil {
+		return "", NewError(models.ErrorConflict, artifact+" version "+strings.TrimSuffix(name, filepath.Ext(name))+" is already published", nil)
+	}
+
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return "", err
+	}
+
+	fileChecksum := checksum(fileBytes)
+	if err := writeStaged(s.paths, folder+name+".sha256", []byte(fileChecksum)); err != nil {
+		return "", err
+	}
+	if err := writeStaged(s.paths, folder+name, fileBytes); err != nil {
+		os.Remove(folder + name + ".sha256")
+		return "", err
+	}
+
+	return fileChecksum, nil
+}
+
+func (s *RegistryService) GetMirrorPlugins() (*models.PluginsResponse, error) {
+	plugins, err := readDirs(s.paths.RegistryPath + "plugin/")
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.PluginsResponse{Success: true, Plugins: []models.Plugin{}}
+	for _, plugin := range plugins {
+		versions, checksums, sizes, err := registryVersions(s.paths.RegistryPath+"plugin/"+plugin+"/", ".jar")
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			continue
+		}
+
+		response.Plugins = append(response.Plugins, models.Plugin{
+			Name:      plugin,
+			Versions:  versions,
+			Checksums: checksums,
+			Sizes:     sizes,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *RegistryService) GetMirrorPluginFile(pluginName, version string) (string, error) {
+	if err := validateNames(pluginName, version); err != nil {
+		return "", err
+	}
+
+	return existingFile(s.paths.RegistryPath + "plugin/" + pluginName + "/" + version + ".jar")
+}
+
+func (s *RegistryService) GetMirrorMaps() (*models.MapsResponse, error) {
+	minigames, err := readDirs(s.paths.RegistryPath + "map/")
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.MapsResponse{Success: true, MiniGames: []models.MiniGames{}}
+	for _, minigame := range minigames {
+		formats, err := readDirs(s.paths.RegistryPath + "map/" + minigame + "/")
+		if err != nil {
+			return nil, err
+		}
+
+		minigameInfo := models.MiniGames{Name: minigame, Formats: []models.Format{}}
+		for _, format := range formats {
+			maps, err := readDirs(s.paths.RegistryPath + "map/" + minigame + "/" + format + "/")
+			if err != nil {
+				return nil, err
+			}
+
+			formatInfo := models.Format{Format: format, Maps: []models.Map{}}
+			for _, mapName := range maps {
+				mapFolder := s.paths.RegistryPath + "map/" + minigame + "/" + format + "/" + mapName + "/"
+				versions, err := readDirs(mapFolder)
+				if err != nil {
+					return nil, err
+				}
+
+				mapInfo := models.Map{
+					Name:      mapName,
+					Versions:  []string{},
+					Checksums: make(map[string]models.MapChecksums),
+					Sizes:     make(map[string]int64),
+				}
+				for _, version := range versions {
+					worldChecksum, err := os.ReadFile(mapFolder + version + "/world.rar.sha256")
+					if err != nil {
+						continue
+					}
+					configChecksum, err := os.ReadFile(mapFolder + version + "/map.yml.sha256")
+					if err != nil {
+						continue
+					}
+					worldInfo, err := os.Stat(mapFolder + version + "/world.rar")
+					if err != nil {
+						continue
+					}
+					configInfo, err := os.Stat(mapFolder + version + "/map.yml")
+					if err != nil {
+						continue
+					}
+
+					mapInfo.Versions = append(mapInfo.Versions, version)
+					mapInfo.Checksums[version] = models.MapChecksums{
+						World:  string(worldChecksum),
+						Config: string(configChecksum),
+					}
+					mapInfo.Sizes[version] = worldInfo.Size() + configInfo.Size()
+				}
+
+				if len(mapInfo.Versions) > 0 {
+					sortVersions(mapInfo.Versions)
+					formatInfo.Maps = append(formatInfo.Maps, mapInfo)
+				}
+			}
+
+			if len(formatInfo.Maps) > 0 {
+				minigameInfo.Formats = append(minigameInfo.Formats, formatInfo)
+			}
+		}
+
+		if len(minigameInfo.Formats) > 0 {
+			response.MiniGames = append(response.MiniGames, minigameInfo)
+		}
+	}
+
+	return response, nil
+}
+
+func (s *RegistryService) GetMirrorMapFile(minigame, format, mapName, version, file string) (string, error) {
+	if err := validateNames(minigame, format, mapName, version); err != nil {
+		return "", err
+	}
+
+	mapFolder := s.paths.RegistryPath + "map/" + minigame + "/" + format + "/" + mapName + "/" + version + "/"
+	switch file {
+	case "world":
+		return existingFile(mapFolder + "world.rar")
+	case "config":
+		return existingFile(mapFolder + "map.yml")
+	}
+
+	return "", NewError(models.ErrorNotFound, "unknown map file "+file, nil)
+}
+
+func (s *RegistryService) GetMirrorPaper() (*models.PaperResponse, error) {
+	versions, checksums, sizes, err := registryVersions(s.paths.RegistryPath+"paper/", ".rar")
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, NewError(models.ErrorNotFound, "no paper versions available", nil)
+	}
+
+	return &models.PaperResponse{Success: true, Versions: versions, Checksums: checksums, Sizes: sizes}, nil
+}
+
+func (s *RegistryService) GetMirrorPaperFile(version string) (string, error) {
+	if err := validateNames(version); err != nil {
+		return "", err
+	}
+
+	return existingFile(s.paths.RegistryPath + "paper/" + version + ".rar")
+}
+
+func (s *RegistryService) GetMirrorVelocity() (*models.VelocityResponse, error) {
+	versions, checksums, sizes, err := registryVersions(s.paths.RegistryPath+"velocity/", ".rar")
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, NewError(models.ErrorNotFound, "no velocity versions available", nil)
+	}
+
+	return &models.VelocityResponse{Success: true, Versions: versions, Checksums: checksums, Sizes: sizes}, nil
+}
+
+func (s *RegistryService) GetMirrorVelocityFile(version string) (string, error) {
+	if err := validateNames(version); err != nil {
+		return "", err
+	}
+
+	return existingFile(s.paths.RegistryPath + "velocity/" + version + ".rar")
+}
+
+func registryVersions(folder, extension string) ([]string, map[string]string, map[string]int64, error) {
+	files, err := os.ReadDir(folder)
+	if os.IsNotExist(err) {
+		return []string{}, nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	versions := []string{}
+	checksums := make(map[string]string)
+	sizes := make(map[string]int64)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), extension) {
+			continue
+		}
+
+		fileChecksum, err := os.ReadFile(folder + file.Name() + ".sha256")
+		if err != nil {
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+
+		version := strings.TrimSuffix(file.Name(), extension)
+		versions = append(versions, version)
+		checksums[version] = string(fileChecksum)
+		sizes[version] = info.Size()
+	}
+	sortVersions(versions)
+
+	return versions, checksums, sizes, nil
+}
+
+func readDirs(folder string) ([]string, error) {
+	entries, err := os.ReadDir(folder)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+
+	return dirs, nil
+}
+
+func sortVersions(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		vi, errI := version.NewVersion(versions[i])
+		vj, errJ := version.NewVersion(versions[j])
+		if errI != nil || errJ != nil {
+			return versions[i] < versions[j]
+		}
+		return vi.LessThan(vj)
+	})
+}
+
+func validatePublish(publishVersion string, names ...string) error {
+	if err := validateNames(append(names, publishVersion)...); err != nil {
+		return err
+	}
+
+	for _, name := range append(names, publishVersion) {
+		if strings.Contains(name, "-") {
+			return NewError(models.ErrorInvalidRequest, "name "+name+" must not contain '-'", nil)
+		}
+	}
+
+	parsed, err := version.NewVersion(publishVersion)
+	if err != nil {
+		return NewError(models.ErrorInvalidRequest, "invalid version "+publishVersion, err)
+	}
+	if parsed.String() != publishVersion {
+		return NewError(models.ErrorInvalidRequest, "version "+publishVersion+" must be published as "+parsed.String(), nil)
+	}
+
+	return nil
+}
+
+func checksum(fileBytes []byte) string {
+	sum := sha256.Sum256(fileBytes)
+	return hex.EncodeToString(sum[:])
+}
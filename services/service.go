@@ -1,48 +1,126 @@
 package services
 
 import (
+	"context"
 	"github.com/mineleaguedev/luximo/models"
+	"log"
+	"time"
 )
 
 type Velocity interface {
-	UpdateVelocity() error
-	GetVelocityVersionsInfo() (*models.VelocityResponse, error)
-	DownloadVelocity(version string) (*[]byte, error)
+	UpdateVelocity(ctx context.Context, tracker Tracker) error
+	GetVelocityVersionsInfo(ctx context.Context) (*models.VelocityResponse, error)
+	DownloadVelocity(ctx context.Context, tracker Tracker, version string) (*[]byte, error)
 	UpdateVelocityVersion(version string, velocityFileBytes []byte) error
 }
 
 type Paper interface {
-	UpdatePaper() error
-	GetPaperVersionsInfo() (*models.PaperResponse, error)
-	DownloadPaper(version string) (*[]byte, error)
+	UpdatePaper(ctx context.Context, tracker Tracker) error
+	GetPaperVersionsInfo(ctx context.Context) (*models.PaperResponse, error)
+	DownloadPaper(ctx context.Context, tracker Tracker, version string) (*[]byte, error)
 	UpdatePaperVersion(version string, paperFileBytes []byte) error
 }
 
 type Plugin interface {
-	UpdatePlugins() error
-	GetPluginsInfo() ([]models.Plugin, error)
-	DownloadPlugin(pluginName, version string) (*[]byte, error)
+	UpdatePlugins(ctx context.Context, tracker Tracker) error
+	GetPluginsInfo(ctx context.Context) ([]models.Plugin, error)
+	DownloadPlugin(ctx context.Context, tracker Tracker, pluginName, version string) (*[]byte, error)
 	UpdatePlugin(pluginName, version string, pluginFileBytes []byte) error
 }
 
 type Map interface {
-	UpdateMaps() error
-	GetMapsInfo() ([]models.MiniGames, error)
-	DownloadMapWorld(minigame, format, minigameMap, version string) (*[]byte, error)
-	DownloadMapConfig(minigame, format, minigameMap, version string) (*[]byte, error)
+	UpdateMaps(ctx context.Context, tracker Tracker) error
+	GetMapsInfo(ctx context.Context) ([]models.MiniGames, error)
+	DownloadMapWorld(ctx context.Context, tracker Tracker, minigame, format, minigameMap, version string) (*[]byte, error)
+	DownloadMapConfig(ctx context.Context, tracker Tracker, minigame, format, minigameMap, version string) (*[]byte, error)
 	UpdateMap(minigame, format, mapName, version string, mapWorldFileBytes, mapConfigFileBytes *[]byte) error
 }
 
+type Mirror interface {
+	GetMirrorPlugins() (*models.PluginsResponse, error)
+	GetMirrorPluginFile(pluginName, version string) (string, error)
+	GetMirrorMaps() (*models.MapsResponse, error)
+	GetMirrorMapFile(minigame, format, mapName, version, file string) (string, error)
+	GetMirrorPaper() (*models.PaperResponse, error)
+	GetMirrorPaperFile(version string) (string, error)
+	GetMirrorVelocity() (*models.VelocityResponse, error)
+	GetMirrorVelocityFile(version string) (string, error)
+}
+
+type Registry interface {
+	PublishPlugin(pluginName, version string, pluginFileBytes []byte) (*models.PublishResponse, error)
+	PublishMap(minigame, format, mapName, version string, mapWorldFileBytes, mapConfigFileBytes []byte) (*models.PublishResponse, error)
+	PublishPaper(version string, paperFileBytes []byte) (*models.PublishResponse, error)
+	PublishVelocity(version string, velocityFileBytes []byte) (*models.PublishResponse, error)
+}
+
+type GC interface {
+	CollectGarbage(ctx context.Context, tracker Tracker) error
+}
+
+type Bandwidth interface {
+	GetBandwidth() models.Bandwidth
+	SetBandwidth(bandwidth models.Bandwidth) error
+}
+
+type Job interface {
+	StartJob(kind string, run func(ctx context.Context, tracker Tracker) error) (models.Job, error)
+	RunJob(kind string, run func(ctx context.Context, tracker Tracker) error) (models.Job, error)
+	GetJob(id string) (models.Job, error)
+	GetJobs() []models.Job
+	Shutdown(ctx context.Context) error
+}
+
+type Event interface {
+	Subscribe(jobID string) (<-chan models.Event, func())
+	Publish(event models.Event)
+	Close()
+}
+
+type Status interface {
+	GetStatus() (bool, []models.SyncStatus)
+}
+
+type Upstream interface {
+	GetUpstreams() []models.Upstream
+}
+
+type Ports interface {
+	GetPorts() []models.PortAssignment
+}
+
+type Supervisor interface {
+	StartServer(serverType, name string) (*models.Process, error)
+	StopServer(serverType, name string) (*models.Process, error)
+	RestartServer(serverType, name string) (*models.Process, error)
+	GetProcess(serverType, name string) (*models.Process, error)
+	GetProcesses() []models.Process
+}
+
 type ProxyServer interface {
+	CreateProxyServer(request models.CreateServerRequest) (*models.Server, error)
+	GetProxyServers() ([]models.Server, error)
+	UpdateProxyServers() error
+	DeleteProxyServer(name string) error
 }
 
 type LobbyServer interface {
+	CreateLobbyServer(request models.CreateServerRequest) (*models.Server, error)
+	GetLobbyServers() ([]models.Server, error)
+	DeleteLobbyServer(name string) error
 }
 
 type MiniServer interface {
+	CreateMiniServer(request models.CreateServerRequest) (*models.Server, error)
+	GetMiniServers() ([]models.Server, error)
+	DeleteMiniServer(name string) error
 }
 
 type MegaServer interface {
+	CreateMegaServer(request models.CreateServerRequest) (*models.Server, error)
+	GetMegaServers() ([]models.Server, error)
+	UpdateMegaServer(name string) (*models.Server, error)
+	DeleteMegaServer(name string) error
 }
 
 type Service struct {
@@ -50,17 +128,138 @@ type Service struct {
 	Paper
 	Plugin
 	Map
+	Mirror
+	Registry
+	GC
+	Bandwidth
+	Job
+	Event
+	Status
+	Upstream
+	Ports
+	Supervisor
 	ProxyServer
 	LobbyServer
 	MiniServer
 	MegaServer
+	paths      models.Paths
+	supervisor *SupervisorService
+	stop       chan struct{}
 }
 
-func NewService(paths models.Paths) *Service {
-	return &Service{
-		Plugin:   NewPluginService(paths),
-		Map:      NewMapService(paths),
-		Velocity: NewVelocityService(paths),
-		Paper:    NewPaperService(paths),
+func NewService(paths models.Paths, config models.Config) (*Service, error) {
+	if err := cleanStaging(paths); err != nil {
+		log.Printf("Error cleaning staging folder: %s", err.Error())
+	}
+
+	events := NewEventService()
+	status := NewStatusService()
+	bandwidth := NewBandwidthLimiter(config)
+	client, err := NewClient(config, bandwidth)
+	if err != nil {
+		return nil, err
+	}
+	listings := NewListingCache(paths, config, client)
+	blobs := NewBlobStore(paths, config)
+	ports := NewPortAllocator(paths, config)
+	supervisor, err := NewSupervisorService(paths, config)
+	if err != nil {
+		return nil, err
 	}
+	proxy := NewProxyServerService(paths, config, supervisor, ports)
+	serversChanged := func() {
+		if err := proxy.UpdateProxyServers(); err != nil {
+			log.Printf("Error updating proxy servers: %s", err.Error())
+		}
+	}
+	supervisor.changed = serversChanged
+	mini := NewMiniServerService(paths, config, supervisor, ports, serversChanged)
+	supervisor.finished = func(serverType, name string) {
+		if serverType == models.ServerMini {
+			mini.cleanup(name)
+		}
+	}
+
+	service := &Service{
+		Plugin:      NewPluginService(paths, client, listings, blobs),
+		Map:         NewMapService(paths, client, listings, blobs),
+		Velocity:    NewVelocityService(paths, client, listings, blobs),
+		Paper:       NewPaperService(paths, client, listings, blobs),
+		Mirror:      NewMirrorService(paths),
+		GC:          NewGCService(paths, config, blobs),
+		Bandwidth:   bandwidth,
+		Job:         NewJobService(paths, config, events, status),
+		Event:       events,
+		Status:      status,
+		Upstream:    client,
+		Ports:       ports,
+		Supervisor:  supervisor,
+		ProxyServer: proxy,
+		LobbyServer: NewLobbyServerService(paths, config, supervisor, ports, serversChanged),
+		MiniServer:  mini,
+		MegaServer:  NewMegaServerService(paths, config, supervisor, ports, serversChanged),
+		paths:       paths,
+		supervisor:  supervisor,
+		stop:        make(chan struct{}),
+	}
+
+	if config.Registry {
+		registry := NewRegistryService(paths)
+		service.Mirror = registry
+		service.Registry = registry
+	}
+
+	if config.GCInterval > 0 {
+		go service.schedule(config.GCInterval, "gc", service.CollectGarbage)
+	}
+
+	if config.SyncInterval > 0 {
+		go service.schedule(config.SyncInterval, "velocity", service.UpdateVelocity)
+		go service.schedule(config.SyncInterval, "paper", service.UpdatePaper)
+		go service.schedule(config.SyncInterval, "plugin", service.UpdatePlugins)
+		go service.schedule(config.SyncInterval, "map", service.UpdateMaps)
+	}
+
+	return service, nil
+}
+
+func (s *Service) schedule(interval time.Duration, kind string, run func(ctx context.Context, tracker Tracker) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			_, err := s.Job.StartJob(kind, func(ctx context.Context, tracker Tracker) error {
+				return run(withScheduled(ctx), tracker)
+			})
+			if err != nil {
+				log.Printf("Error starting scheduled %s job: %s", kind, err.Error())
+			}
+		}
+	}
+}
+
+func (s *Service) Shutdown(ctx context.Context) error {
+	close(s.stop)
+
+	stopped := make(chan struct{})
+	go func() {
+		if err := s.supervisor.Shutdown(ctx); err != nil {
+			log.Printf("Error stopping servers: %s", err.Error())
+		}
+		close(stopped)
+	}()
+
+	err := s.Job.Shutdown(ctx)
+	s.Event.Close()
+	<-stopped
+
+	if err := cleanStaging(s.paths); err != nil {
+		log.Printf("Error cleaning staging folder: %s", err.Error())
+	}
+
+	return err
 }
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"github.com/mineleaguedev/luximo/models"
+	"os"
+)
+
+func writeStaged(paths models.Paths, target string, data []byte) error {
+	file, err := os.CreateTemp(paths.StagingPath, "*.part")
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := os.Rename(file.Name(), target); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return nil
+}
+
+func cleanStaging(paths models.Paths) error {
+	stagingFiles, err := os.ReadDir(paths.StagingPath)
+	if err != nil {
+		return err
+	}
+
+	for _, stagingFile := range stagingFiles {
+		if err := os.RemoveAll(paths.StagingPath + stagingFile.Name()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
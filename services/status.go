@@ -0,0 +1,66 @@
+package services
+
+import (
+	"github.com/mineleaguedev/luximo/models"
+	"sort"
+	"sync"
+)
+
+type StatusService struct {
+	mutex sync.RWMutex
+	syncs map[string]*models.SyncStatus
+}
+
+func NewStatusService() *StatusService {
+	return &StatusService{syncs: make(map[string]*models.SyncStatus)}
+}
+
+func (s *StatusService) JobFinished(job models.Job) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	status, ok := s.syncs[job.Kind]
+	if !ok {
+		status = &models.SyncStatus{Kind: job.Kind}
+		s.syncs[job.Kind] = status
+	}
+
+	switch job.State {
+	case models.JobSucceeded:
+		status.LastJob = job.ID
+		status.Error = ""
+		status.ErrorCode = ""
+		if job.Report.StaleSince != nil {
+			status.State = models.SyncStale
+			status.StaleSince = job.Report.StaleSince
+		} else {
+			status.State = models.SyncOK
+			status.StaleSince = nil
+			status.LastSync = job.FinishedAt
+		}
+	case models.JobFailed:
+		status.LastJob = job.ID
+		status.State = models.SyncFailed
+		status.Error = job.Error
+		status.ErrorCode = job.ErrorCode
+	}
+}
+
+func (s *StatusService) GetStatus() (bool, []models.SyncStatus) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ready := true
+	syncs := make([]models.SyncStatus, 0, len(s.syncs))
+	for _, status := range s.syncs {
+		if status.State == models.SyncFailed {
+			ready = false
+		}
+		syncs = append(syncs, *status)
+	}
+	sort.Slice(syncs, func(i, j int) bool {
+		return syncs[i].Kind < syncs[j].Kind
+	})
+
+	return ready, syncs
+}
@@ -0,0 +1,588 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/mineleaguedev/luximo/models"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const maxCrashes = 10
+
+type SupervisorService struct {
+	paths     models.Paths
+	config    models.SupervisorConfig
+	ready     map[string]*regexp.Regexp
+	changed   func()
+	finished  func(serverType, name string)
+	mutex     sync.Mutex
+	closed    bool
+	processes map[string]*process
+}
+
+type process struct {
+	info     models.Process
+	folder   string
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	console  *consoleLog
+	output   *outputTail
+	done     chan struct{}
+	stopping bool
+	stage    string
+	restart  *time.Timer
+}
+
+func NewSupervisorService(paths models.Paths, config models.Config) (*SupervisorService, error) {
+	for serverType, policy := range config.Supervisor.RestartPolicy {
+		switch policy {
+		case models.RestartAlways, models.RestartOnFailure, models.RestartNever:
+		default:
+			return nil, NewError(models.ErrorInvalidConfig, "unknown restart policy "+policy+" for "+serverType+" servers", nil)
+		}
+	}
+
+	ready := make(map[string]*regexp.Regexp)
+	for kind, pattern := range config.Supervisor.ReadyPatterns {
+		if pattern == "" {
+			continue
+		}
+
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, NewError(models.ErrorInvalidConfig, "invalid ready pattern for "+kind, err)
+		}
+		ready[kind] = compiled
+	}
+
+	return &SupervisorService{
+		paths:     paths,
+		config:    config.Supervisor,
+		ready:     ready,
+		changed:   func() {},
+		finished:  func(string, string) {},
+		processes: make(map[string]*process),
+	}, nil
+}
+
+func (s *SupervisorService) StartServer(serverType, name string) (*models.Process, error) {
+	folder, err := s.serverFolder(serverType, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil, NewError(models.ErrorShuttingDown, "luximo is shutting down", nil)
+	}
+
+	key := serverType + "/" + name
+	previous, ok := s.processes[key]
+	if ok && previous.alive() {
+		return nil, NewError(models.ErrorConflict, serverType+" server "+name+" is already running", nil)
+	}
+
+	server, err := loadServer(folder)
+	if err != nil {
+		return nil, err
+	}
+	if !portFree("tcp", server.Port) {
+		return nil, NewError(models.ErrorConflict, "port "+strconv.Itoa(server.Port)+" of "+serverType+" server "+name+" is already in use", nil)
+	}
+	if ok {
+		previous.cancelRestart()
+		previous.info.Restarts = 0
+	}
+
+	p, err := s.launch(key, serverType, name, folder, previous)
+	if err != nil {
+		return nil, err
+	}
+
+	info := p.info
+	return &info, nil
+}
+
+func (s *SupervisorService) launch(key, serverType, name, folder string, previous *process) (*process, error) {
+	console, err := openConsoleLog(folder+"logs/console.log", s.config.LogMaxSize, s.config.LogMaxFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(s.config.Java, s.command(serverType)...)
+	cmd.Dir = folder
+
+	p := &process{
+		info:    models.Process{Name: name, Type: serverType, State: models.ProcessStarting},
+		folder:  folder,
+		cmd:     cmd,
+		console: console,
+		output:  &outputTail{},
+		done:    make(chan struct{}),
+	}
+	ready := s.ready["paper"]
+	if serverType == models.ServerProxy {
+		ready = s.ready["velocity"]
+	}
+	if ready != nil {
+		p.output.ready = ready
+		p.output.onReady = func() {
+			s.markReady(key, p)
+		}
+	}
+	cmd.Stdout = io.MultiWriter(console, p.output)
+	cmd.Stderr = cmd.Stdout
+
+	p.stdin, err = cmd.StdinPipe()
+	if err != nil {
+		console.Close()
+		return nil, err
+	}
+	if previous != nil {
+		p.info.Restarts = previous.info.Restarts
+		p.info.Crashes = previous.info.Crashes
+	}
+	s.processes[key] = p
+
+	if err := cmd.Start(); err != nil {
+		console.Close()
+		stoppedAt := time.Now().UTC()
+		p.info.State = models.ProcessCrashed
+		p.info.StoppedAt = &stoppedAt
+		close(p.done)
+		return nil, NewError(models.ErrorInternal, "error starting "+serverType+" server "+name, err)
+	}
+
+	startedAt := time.Now().UTC()
+	p.info.PID = cmd.Process.Pid
+	p.info.StartedAt = &startedAt
+	if ready == nil {
+		p.info.State = models.ProcessRunning
+		p.info.Ready = true
+		p.info.ReadyAt = &startedAt
+		go s.changed()
+	}
+	go s.wait(key, p)
+
+	return p, nil
+}
+
+func (s *SupervisorService) markReady(key string, p *process) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.processes[key] != p || !p.alive() || p.stopping {
+		return
+	}
+
+	readyAt := time.Now().UTC()
+	p.info.State = models.ProcessRunning
+	p.info.Ready = true
+	p.info.ReadyAt = &readyAt
+	log.Printf("Server %s is ready after %s", key, readyAt.Sub(*p.info.StartedAt).Round(time.Millisecond))
+
+	go s.changed()
+}
+
+func (s *SupervisorService) StopServer(serverType, name string) (*models.Process, error) {
+	if _, err := s.serverFolder(serverType, name); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	p, ok := s.processes[serverType+"/"+name]
+	if ok && !p.alive() && p.cancelRestart() {
+		p.info.State = models.ProcessStopped
+		info := p.info
+		s.mutex.Unlock()
+		return &info, nil
+	}
+	if !ok || !p.alive() {
+		s.mutex.Unlock()
+		return nil, NewError(models.ErrorConflict, serverType+" server "+name+" is not running", nil)
+	}
+	s.mutex.Unlock()
+
+	return s.stop(p), nil
+}
+
+func (s *SupervisorService) RestartServer(serverType, name string) (*models.Process, error) {
+	if _, err := s.serverFolder(serverType, name); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	p, ok := s.processes[serverType+"/"+name]
+	if ok {
+		p.cancelRestart()
+	}
+	s.mutex.Unlock()
+
+	if ok {
+		s.stop(p)
+	}
+
+	return s.StartServer(serverType, name)
+}
+
+func (s *SupervisorService) GetProcess(serverType, name string) (*models.Process, error) {
+	if _, err := s.serverFolder(serverType, name); err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, ok := s.processes[serverType+"/"+name]
+	if !ok {
+		return &models.Process{Name: name, Type: serverType, State: models.ProcessStopped}, nil
+	}
+
+	return p.describe(), nil
+}
+
+func (s *SupervisorService) GetProcesses() []models.Process {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	processes := make([]models.Process, 0, len(s.processes))
+	for _, p := range s.processes {
+		processes = append(processes, *p.describe())
+	}
+	sort.Slice(processes, func(i, j int) bool {
+		if processes[i].Type != processes[j].Type {
+			return processes[i].Type < processes[j].Type
+		}
+		return processes[i].Name < processes[j].Name
+	})
+
+	return processes
+}
+
+func (s *SupervisorService) Running(serverType, name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, ok := s.processes[serverType+"/"+name]
+	return ok && (p.alive() || p.restart != nil)
+}
+
+func (s *SupervisorService) Ready(serverType, name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, ok := s.processes[serverType+"/"+name]
+	return ok && p.alive() && p.info.Ready
+}
+
+func (s *SupervisorService) SendCommand(serverType, name, command string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	p, ok := s.processes[serverType+"/"+name]
+	if !ok || !p.alive() || p.stopping {
+		return false, nil
+	}
+
+	_, err := io.WriteString(p.stdin, command+"\n")
+	return err == nil, err
+}
+
+func (s *SupervisorService) Forget(serverType, name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if p, ok := s.processes[serverType+"/"+name]; ok {
+		p.cancelRestart()
+	}
+	delete(s.processes, serverType+"/"+name)
+}
+
+func (s *SupervisorService) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.closed = true
+	var running []*process
+	for _, p := range s.processes {
+		p.cancelRestart()
+		if p.alive() {
+			running = append(running, p)
+		}
+	}
+	s.mutex.Unlock()
+
+	var wg sync.WaitGroup
+	for _, p := range running {
+		wg.Add(1)
+		go func(p *process) {
+			defer wg.Done()
+			s.stop(p)
+		}(p)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+	}
+
+	log.Printf("Grace period expired, killing remaining servers")
+	for _, p := range running {
+		s.setStage(p, models.StopSigkill)
+		p.cmd.Process.Kill()
+	}
+	<-stopped
+
+	return ctx.Err()
+}
+
+func (s *SupervisorService) stop(p *process) *models.Process {
+	s.mutex.Lock()
+	terminate := p.alive() && !p.stopping
+	if terminate {
+		p.stopping = true
+		p.info.State = models.ProcessStopping
+		if p.info.Ready {
+			p.info.Ready = false
+			go s.changed()
+		}
+	}
+	s.mutex.Unlock()
+
+	if terminate {
+		s.terminate(p)
+	}
+	<-p.done
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return p.describe()
+}
+
+func (s *SupervisorService) terminate(p *process) {
+	command := "stop"
+	if p.info.Type == models.ServerProxy {
+		command = "end"
+	}
+
+	s.setStage(p, models.StopCommand)
+	if _, err := io.WriteString(p.stdin, command+"\n"); err == nil && p.exited(s.config.StopTimeout) {
+		return
+	}
+
+	s.setStage(p, models.StopSigterm)
+	if err := p.cmd.Process.Signal(syscall.SIGTERM); err == nil && p.exited(s.config.KillTimeout) {
+		return
+	}
+
+	s.setStage(p, models.StopSigkill)
+	p.cmd.Process.Kill()
+}
+
+func (s *SupervisorService) setStage(p *process, stage string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if p.stage != models.StopSigkill {
+		p.stage = stage
+	}
+}
+
+func (s *SupervisorService) wait(key string, p *process) {
+	err := p.cmd.Wait()
+	p.console.Close()
+
+	exitCode := p.cmd.ProcessState.ExitCode()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		exitCode = -1
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	defer s.finish(key, p)
+
+	if p.info.Ready {
+		go s.changed()
+	}
+
+	stoppedAt := time.Now().UTC()
+	p.info.Ready = false
+	p.info.ExitCode = &exitCode
+	p.info.StoppedAt = &stoppedAt
+	p.info.Output = p.output.Lines()
+	if p.stopping {
+		p.info.StoppedBy = p.stage
+		log.Printf("Server %s stopped by %s with exit code %d", key, p.stage, exitCode)
+	}
+	if p.stopping || exitCode == 0 {
+		p.info.State = models.ProcessStopped
+	} else {
+		p.info.State = models.ProcessCrashed
+		p.info.Crashes = append(p.info.Crashes, models.Crash{ExitCode: exitCode, At: stoppedAt, Output: p.info.Output})
+		if len(p.info.Crashes) > maxCrashes {
+			p.info.Crashes = p.info.Crashes[len(p.info.Crashes)-maxCrashes:]
+		}
+	}
+	close(p.done)
+
+	if p.stopping || s.closed || s.processes[key] != p {
+		return
+	}
+
+	switch s.config.RestartPolicy[p.info.Type] {
+	case models.RestartAlways:
+	case models.RestartOnFailure:
+		if exitCode == 0 {
+			return
+		}
+	default:
+		return
+	}
+
+	if s.config.RestartReset > 0 && stoppedAt.Sub(*p.info.StartedAt) >= s.config.RestartReset {
+		p.info.Restarts = 0
+	}
+	s.scheduleRestart(key, p, exitCode)
+}
+
+func (s *SupervisorService) scheduleRestart(key string, p *process, exitCode int) {
+	if s.config.RestartLimit > 0 && p.info.Restarts >= s.config.RestartLimit {
+		p.info.State = models.ProcessFailed
+		log.Printf("Server %s failed after %d restarts, last exit code %d", key, p.info.Restarts, exitCode)
+		return
+	}
+
+	delay := s.config.RestartBackoff
+	for i := 0; i < p.info.Restarts && delay <= math.MaxInt64/2; i++ {
+		if s.config.RestartMaxBackoff > 0 && delay >= s.config.RestartMaxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	if s.config.RestartMaxBackoff > 0 && delay > s.config.RestartMaxBackoff {
+		delay = s.config.RestartMaxBackoff
+	}
+
+	restartAt := time.Now().UTC().Add(delay)
+	p.info.RestartAt = &restartAt
+	log.Printf("Server %s exited with code %d, restarting in %s", key, exitCode, delay)
+	p.restart = time.AfterFunc(delay, func() {
+		s.autoRestart(key, p)
+	})
+}
+
+func (s *SupervisorService) autoRestart(key string, previous *process) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed || s.processes[key] != previous || previous.restart == nil {
+		return
+	}
+	previous.restart = nil
+	previous.info.RestartAt = nil
+	previous.info.Restarts++
+
+	if _, err := s.launch(key, previous.info.Type, previous.info.Name, previous.folder, previous); err != nil {
+		log.Printf("Error restarting server %s: %s", key, err.Error())
+		s.scheduleRestart(key, s.processes[key], -1)
+	}
+}
+
+func (s *SupervisorService) finish(key string, p *process) {
+	if p.stopping || p.info.ExitCode == nil || *p.info.ExitCode != 0 || p.restart != nil || s.closed || s.processes[key] != p {
+		return
+	}
+
+	go s.finished(p.info.Type, p.info.Name)
+}
+
+func (s *SupervisorService) command(serverType string) []string {
+	args := append([]string{}, s.config.JavaArgs...)
+	if serverType == models.ServerProxy {
+		return append(args, "-jar", s.config.VelocityJar)
+	}
+
+	return append(args, "-jar", s.config.PaperJar, "nogui")
+}
+
+func (s *SupervisorService) serverFolder(serverType, name string) (string, error) {
+	if err := validateNames(name); err != nil {
+		return "", err
+	}
+
+	root, err := serverRoot(s.paths, serverType)
+	if err != nil {
+		return "", err
+	}
+
+	folder := root + name + "/"
+	if _, err := os.Stat(folder + serverMetadataFile); err != nil {
+		if os.IsNotExist(err) {
+			return "", NewError(models.ErrorNotFound, serverType+" server "+name+" not found", nil)
+		}
+		return "", err
+	}
+
+	return folder, nil
+}
+
+func (p *process) describe() *models.Process {
+	info := p.info
+	if p.alive() {
+		info.Output = p.output.Lines()
+	}
+
+	return &info
+}
+
+func (p *process) cancelRestart() bool {
+	if p.restart == nil {
+		return false
+	}
+
+	p.restart.Stop()
+	p.restart = nil
+	p.info.RestartAt = nil
+
+	return true
+}
+
+func (p *process) exited(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-p.done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (p *process) alive() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
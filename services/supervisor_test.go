@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mineleaguedev/luximo/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const fakeJava = `#!/bin/sh
+if [ -f crash ]; then
+	echo "Exception in server tick loop"
+	exit 1
+fi
+if [ -f stubborn ]; then
+	trap '' TERM
+fi
+echo 'Done (0.100s)! For help, type "help"'
+while read line; do
+	if [ "$line" = stop ] && [ ! -f deaf ] && [ ! -f stubborn ]; then
+		echo "Stopping server"
+		exit 0
+	fi
+done
+`
+
+func newTestSupervisor(t *testing.T, markers ...string) *SupervisorService {
+	t.Helper()
+
+	root := t.TempDir() + "/"
+	paths := models.Paths{
+		Path:             root,
+		LobbyServersPath: root + "lobby/",
+		MiniServersPath:  root + "mini/",
+		MegaServersPath:  root + "mega/",
+		ProxyServersPath: root + "proxy/",
+	}
+
+	java := filepath.Join(root, "java")
+	if err := os.WriteFile(java, []byte(fakeJava), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	folder := paths.LobbyServersPath + "lobby-1/"
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	serverBytes, err := json.Marshal(models.Server{Name: "lobby-1", Type: models.ServerLobby})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(folder+serverMetadataFile, serverBytes, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, marker := range markers {
+		if err := os.WriteFile(folder+marker, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	supervisor, err := NewSupervisorService(paths, models.Config{Supervisor: models.SupervisorConfig{
+		Java:              java,
+		PaperJar:          "paper.jar",
+		RestartPolicy:     map[string]string{models.ServerLobby: models.RestartOnFailure},
+		RestartBackoff:    10 * time.Millisecond,
+		RestartMaxBackoff: 20 * time.Millisecond,
+		RestartLimit:      2,
+		ReadyPatterns:     map[string]string{"paper": `Done \(`},
+		StopTimeout:       300 * time.Millisecond,
+		KillTimeout:       300 * time.Millisecond,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		supervisor.Shutdown(context.Background())
+	})
+
+	return supervisor
+}
+
+func waitForProcess(t *testing.T, supervisor *SupervisorService, done func(*models.Process) bool) *models.Process {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		p, err := supervisor.GetProcess(models.ServerLobby, "lobby-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done(p) {
+			return p
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for process, last state %s", p.State)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSupervisorGracefulStop(t *testing.T) {
+	supervisor := newTestSupervisor(t)
+
+	if _, err := supervisor.StartServer(models.ServerLobby, "lobby-1"); err != nil {
+		t.Fatal(err)
+	}
+	waitForProcess(t, supervisor, func(p *models.Process) bool {
+		return p.State == models.ProcessRunning && p.Ready
+	})
+
+	p, err := supervisor.StopServer(models.ServerLobby, "lobby-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.State != models.ProcessStopped || p.StoppedBy != models.StopCommand {
+		t.Fatalf("expected stopped by %s, got %s by %s", models.StopCommand, p.State, p.StoppedBy)
+	}
+	if p.ExitCode == nil || *p.ExitCode != 0 {
+		t.Fatalf("expected exit code 0, got %v", p.ExitCode)
+	}
+}
+
+func TestSupervisorStopEscalation(t *testing.T) {
+	tests := []struct {
+		marker    string
+		stoppedBy string
+	}{
+		{marker: "deaf", stoppedBy: models.StopSigterm},
+		{marker: "stubborn", stoppedBy: models.StopSigkill},
+	}
+
+	for _, test := range tests {
+		t.Run(test.marker, func(t *testing.T) {
+			supervisor := newTestSupervisor(t, test.marker)
+
+			if _, err := supervisor.StartServer(models.ServerLobby, "lobby-1"); err != nil {
+				t.Fatal(err)
+			}
+			waitForProcess(t, supervisor, func(p *models.Process) bool {
+				return p.Ready
+			})
+
+			p, err := supervisor.StopServer(models.ServerLobby, "lobby-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.State != models.ProcessStopped || p.StoppedBy != test.stoppedBy {
+				t.Fatalf("expected stopped by %s, got %s by %s", test.stoppedBy, p.State, p.StoppedBy)
+			}
+		})
+	}
+}
+
+func TestSupervisorCrashLoop(t *testing.T) {
+	supervisor := newTestSupervisor(t, "crash")
+
+	if _, err := supervisor.StartServer(models.ServerLobby, "lobby-1"); err != nil {
+		t.Fatal(err)
+	}
+	p := waitForProcess(t, supervisor, func(p *models.Process) bool {
+		return p.State == models.ProcessFailed
+	})
+
+	if p.Restarts != 2 {
+		t.Fatalf("expected 2 restarts, got %d", p.Restarts)
+	}
+	if len(p.Crashes) != 3 {
+		t.Fatalf("expected 3 crashes, got %d", len(p.Crashes))
+	}
+	if p.Crashes[0].ExitCode != 1 {
+		t.Fatalf("expected exit code 1, got %d", p.Crashes[0].ExitCode)
+	}
+	if supervisor.Running(models.ServerLobby, "lobby-1") {
+		t.Fatal("expected failed server not to be running")
+	}
+}
+
+func TestSupervisorShutdownGracePeriod(t *testing.T) {
+	supervisor := newTestSupervisor(t, "stubborn")
+
+	if _, err := supervisor.StartServer(models.ServerLobby, "lobby-1"); err != nil {
+		t.Fatal(err)
+	}
+	waitForProcess(t, supervisor, func(p *models.Process) bool {
+		return p.Ready
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	startedAt := time.Now()
+	if err := supervisor.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(startedAt); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected shutdown within the grace period, took %s", elapsed)
+	}
+
+	p, err := supervisor.GetProcess(models.ServerLobby, "lobby-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.State != models.ProcessStopped || p.StoppedBy != models.StopSigkill {
+		t.Fatalf("expected stopped by %s, got %s by %s", models.StopSigkill, p.State, p.StoppedBy)
+	}
+}
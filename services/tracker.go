@@ -0,0 +1,31 @@
+package services
+
+import (
+	"io"
+	"time"
+)
+
+type Tracker interface {
+	ListingFetched(items int, notModified bool)
+	Stale(since time.Time)
+	Planned(items int)
+	DownloadStarted(name string, size int64)
+	DownloadProgress(name string, n int64)
+	DownloadFinished(name string)
+	Installed(name string)
+	Removed(name string)
+}
+
+type progressReader struct {
+	reader  io.Reader
+	name    string
+	tracker Tracker
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.tracker.DownloadProgress(r.name, int64(n))
+	}
+	return n, err
+}
@@ -1,30 +1,65 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"github.com/hashicorp/go-version"
 	"github.com/mineleaguedev/luximo/models"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"sort"
 	"strings"
 )
 
 type VelocityService struct {
-	paths models.Paths
+	paths    models.Paths
+	client   *Client
+	listings *ListingCache
+	blobs    *BlobStore
 }
 
-func NewVelocityService(paths models.Paths) *VelocityService {
-	return &VelocityService{paths: paths}
+func NewVelocityService(paths models.Paths, client *Client, listings *ListingCache, blobs *BlobStore) *VelocityService {
+	return &VelocityService{paths: paths, client: client, listings: listings, blobs: blobs}
 }
 
-func (s *VelocityService) UpdateVelocity() error {
-	velocityInfo, err := s.GetVelocityVersionsInfo()
+func (s *VelocityService) UpdateVelocity(ctx context.Context, tracker Tracker) error {
+	velocityInfo, listing, err := s.getVelocityVersionsInfo(ctx)
 	if err != nil {
 		return err
 	}
+	tracker.ListingFetched(len(velocityInfo.Versions), listing.NotModified)
+
+	if listing.Stale {
+		tracker.Stale(listing.FetchedAt)
+		return nil
+	}
+
+	if listing.NotModified {
+		return nil
+	}
+
+	if err := s.syncVelocity(ctx, tracker, velocityInfo); err != nil {
+		return err
+	}
+
+	return s.listings.MarkSynced("velocity")
+}
+
+func (s *VelocityService) syncVelocity(ctx context.Context, tracker Tracker, velocityInfo *models.VelocityResponse) error {
+	lastVersion := velocityInfo.LastVersion
+	checksum := velocityInfo.Checksums[lastVersion]
+	size := velocityInfo.Sizes[lastVersion]
+	if !s.blobs.Has("velocity/"+lastVersion, checksum) {
+		var err error
+		size, err = artifactSize(ctx, s.client, size, "/velocity/"+lastVersion)
+		if err != nil {
+			return err
+		}
+
+		if err := s.blobs.EnsureSpace(size); err != nil {
+			return err
+		}
+	}
 
 	velocityVersions, err := os.ReadDir(s.paths.VelocityPath)
 	if err != nil {
@@ -36,18 +71,10 @@ func (s *VelocityService) UpdateVelocity() error {
 			if err := os.RemoveAll(s.paths.VelocityPath + velocityVersion.Name()); err != nil {
 				return err
 			}
+			tracker.Removed(velocityVersion.Name())
 		}
 
-		velocityFileBytes, err := s.DownloadVelocity(velocityInfo.LastVersion)
-		if err != nil {
-			return err
-		}
-
-		if err := s.UpdateVelocityVersion(velocityInfo.LastVersion, *velocityFileBytes); err != nil {
-			return err
-		}
-
-		return nil
+		return s.installVelocity(ctx, tracker, lastVersion, checksum, size)
 	}
 
 	for _, velocityVersion := range velocityVersions {
@@ -55,6 +82,7 @@ func (s *VelocityService) UpdateVelocity() error {
 			if err := os.RemoveAll(s.paths.VelocityPath + velocityVersion.Name()); err != nil {
 				return err
 			}
+			tracker.Removed(velocityVersion.Name())
 			continue
 		}
 
@@ -62,92 +90,109 @@ func (s *VelocityService) UpdateVelocity() error {
 		velocityVersion := strings.ReplaceAll(velocityFileName[1], ".rar", "")
 
 		if velocityVersion != velocityInfo.LastVersion {
-			velocityFileBytes, err := s.DownloadVelocity(velocityInfo.LastVersion)
-			if err != nil {
-				return err
-			}
+			return s.installVelocity(ctx, tracker, lastVersion, checksum, size)
+		}
+	}
 
-			if err := s.UpdateVelocityVersion(velocityInfo.LastVersion, *velocityFileBytes); err != nil {
-				return err
-			}
+	return s.installVelocity(ctx, tracker, lastVersion, checksum, size)
+}
+
+func (s *VelocityService) installVelocity(ctx context.Context, tracker Tracker, version, expected string, size int64) error {
+	tracker.Planned(1)
 
-			return nil
+	if sum, ok := s.blobs.Lookup("velocity/"+version, expected); ok {
+		if err := s.blobs.Link(sum, s.paths.VelocityPath+"velocity-"+version+".rar"); err != nil {
+			return err
 		}
+		tracker.Installed("velocity-" + version)
+		return nil
 	}
 
-	velocityFileBytes, err := s.DownloadVelocity(velocityInfo.LastVersion)
+	size, err := artifactSize(ctx, s.client, size, "/velocity/"+version)
 	if err != nil {
 		return err
 	}
 
-	if err := s.UpdateVelocityVersion(velocityInfo.LastVersion, *velocityFileBytes); err != nil {
+	if err := s.blobs.EnsureSpace(size); err != nil {
 		return err
 	}
 
+	velocityFileBytes, err := s.DownloadVelocity(ctx, tracker, version)
+	if err != nil {
+		return err
+	}
+
+	if err := s.blobs.Verify("velocity-"+version, expected, *velocityFileBytes); err != nil {
+		return err
+	}
+
+	if err := s.UpdateVelocityVersion(version, *velocityFileBytes); err != nil {
+		return err
+	}
+
+	tracker.Installed("velocity-" + version)
 	return nil
 }
 
-func (s *VelocityService) GetVelocityVersionsInfo() (*models.VelocityResponse, error) {
-	resp, err := http.Get("https://api.mineleague.ru/velocity")
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+func (s *VelocityService) GetVelocityVersionsInfo(ctx context.Context) (*models.VelocityResponse, error) {
+	velocityInfo, _, err := s.getVelocityVersionsInfo(ctx)
+	return velocityInfo, err
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
+func (s *VelocityService) getVelocityVersionsInfo(ctx context.Context) (*models.VelocityResponse, *Listing, error) {
+	listing, err := s.listings.Fetch(ctx, "velocity", "/velocity")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var response models.VelocityResponse
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, err
+	if err := json.Unmarshal(listing.Body, &response); err != nil {
+		return nil, nil, NewError(models.ErrorUpstreamRejected, "invalid response from API", err)
 	}
 
 	if !response.Success {
-		return nil, errors.New("error getting velocity versions list from API")
+		return nil, nil, NewError(models.ErrorUpstreamRejected, "error getting velocity versions list from API", nil)
+	}
+
+	if len(response.Versions) == 0 {
+		return nil, nil, NewError(models.ErrorUpstreamRejected, "no velocity versions available from API", nil)
 	}
 
 	versions := make([]*version.Version, len(response.Versions))
 	for i, raw := range response.Versions {
-		v, _ := version.NewVersion(raw)
+		v, err := version.NewVersion(raw)
+		if err != nil {
+			return nil, nil, NewError(models.ErrorUpstreamRejected, "invalid velocity version "+raw+" from API", err)
+		}
 		versions[i] = v
 	}
 	sort.Sort(version.Collection(versions))
 	response.LastVersion = versions[len(versions)-1].String()
 
-	return &response, nil
+	return &response, listing, nil
 }
 
-func (s *VelocityService) DownloadVelocity(version string) (*[]byte, error) {
-	resp, err := http.Get("https://api.mineleague.ru/velocity/" + version)
+func (s *VelocityService) DownloadVelocity(ctx context.Context, tracker Tracker, version string) (*[]byte, error) {
+	resp, err := s.client.Get(ctx, "/velocity/"+version)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return nil, errors.New("error downloading velocity from API")
+		return nil, upstreamStatusError("error downloading velocity from API", resp)
 	}
 
-	fileBytes, err := ioutil.ReadAll(resp.Body)
+	tracker.DownloadStarted("velocity-"+version, resp.ContentLength)
+	fileBytes, err := ioutil.ReadAll(&progressReader{reader: resp.Body, name: "velocity-" + version, tracker: tracker})
 	if err != nil {
 		return nil, err
 	}
+	tracker.DownloadFinished("velocity-" + version)
 
 	return &fileBytes, nil
 }
 
 func (s *VelocityService) UpdateVelocityVersion(version string, velocityFileBytes []byte) error {
-	file, err := os.Create(s.paths.VelocityPath + "velocity-" + version + ".rar")
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	if _, err = file.Write(velocityFileBytes); err != nil {
-		return err
-	}
-
-	return nil
+	return s.blobs.Install("velocity/"+version, s.paths.VelocityPath+"velocity-"+version+".rar", velocityFileBytes)
 }